@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+var errHashChanged = errors.New("data hash changed")
+
+// convert decodes the envelopes of one format into EnvelopeT and writes
+// them in another, envelopes whose data hash does not survive the
+// conversion are reported and left out.
+func (c *cli) convert(args []string) error {
+	fs := c.flags("convert", "[files]")
+	names := strings.Join(formatNames(), ", ")
+	from := fs.String("from", "json", "input format: "+names)
+	to := fs.String("to", "", "output format: "+names)
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	in, inFound := formats[*from]
+	out, outFound := formats[*to]
+	if !inFound || !outFound {
+		fs.Usage()
+		return errUsage
+	}
+	failed := 0
+	err := c.inputs(fs.Args(), func(name string, r io.Reader) error {
+		idx := 0
+		var writeErr error
+		err := in.decode(r, func(v interface{}) error {
+			label := fmt.Sprintf("%s:%d", name, idx)
+			idx++
+			env, buf, err := convertEnvelope(v, out)
+			if env != nil && env.ID != "" {
+				label = env.ID
+			}
+			if err != nil {
+				failed++
+				fmt.Fprintf(c.stderr, "c5 convert: %s: %v\n", label, err)
+				return nil
+			}
+			_, writeErr = c.stdout.Write(buf)
+			return writeErr
+		})
+		if err != nil && err != writeErr {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return errFailed
+	}
+	return nil
+}
+
+func envelopeOf(v interface{}) (*c5.EnvelopeT, error) {
+	val, err := dictValue(v)
+	if err != nil {
+		return nil, err
+	}
+	dict, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: envelope is no object", c5.ErrDictType)
+	}
+	env := c5.EnvelopeT{}
+	if err := c5.FromDictEnvelopeT(dict, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+func dataHash(env *c5.EnvelopeT) string {
+	return c5.NewSimpleEnvelopeFromEnvelopeT(env, nil).DataHash()
+}
+
+// convertEnvelope encodes v in out and decodes the result again to check
+// that the data hash is preserved.
+func convertEnvelope(v interface{}, out format) (*c5.EnvelopeT, []byte, error) {
+	env, err := envelopeOf(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := dataHash(env)
+	dict, err := dictValue(env.ToDict())
+	if err != nil {
+		return env, nil, err
+	}
+	buf := bytes.Buffer{}
+	if err := out.encode(&buf, dict); err != nil {
+		return env, nil, err
+	}
+	decoded := []*c5.EnvelopeT{}
+	err = out.decode(bytes.NewReader(buf.Bytes()), func(v interface{}) error {
+		back, err := envelopeOf(v)
+		decoded = append(decoded, back)
+		return err
+	})
+	if err != nil {
+		return env, nil, fmt.Errorf("%w: %v", errHashChanged, err)
+	}
+	if len(decoded) != 1 {
+		return env, nil, fmt.Errorf("%w: %d envelopes decoded", errHashChanged, len(decoded))
+	}
+	if back := dataHash(decoded[0]); back != hash {
+		return env, nil, fmt.Errorf("%w: %s became %s", errHashChanged, hash, back)
+	}
+	return env, buf.Bytes(), nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConvertSuite struct {
+	suite.Suite
+	envelopes string
+}
+
+func (s *ConvertSuite) SetupTest() {
+	_, s.envelopes, _ = exec("{\"name\":\"object\",\"date\":\"2021-05-20\"}\n{\"big\":123456789012345678901234567890,\"f\":1.50}\n",
+		"wrap", "--src", "test case", "--kind", "test", "--t", "1624140000000")
+}
+
+func (s *ConvertSuite) TestRoundTrip() {
+	for _, to := range []string{"cbor", "yaml", "json-indent", "json"} {
+		code, converted, stderr := exec(s.envelopes, "convert", "--to", to)
+		assert.Equal(s.T(), 0, code, stderr)
+		code, back, stderr := exec(converted, "convert", "--from", to, "--to", "json")
+		assert.Equal(s.T(), 0, code, stderr)
+		assert.Equal(s.T(), s.envelopes, back, to)
+	}
+}
+
+func (s *ConvertSuite) TestYaml() {
+	code, stdout, _ := exec(s.envelopes, "convert", "--to", "yaml")
+	assert.Equal(s.T(), 0, code)
+	assert.True(s.T(), strings.HasPrefix(stdout, `---
+data:
+  data:
+    date: "2021-05-20"
+    name: object
+  kind: test
+dst: []
+id: 1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp
+`), stdout)
+	assert.Contains(s.T(), stdout, "big: !!int 123456789012345678901234567890\n    f: 1.5\n")
+}
+
+func (s *ConvertSuite) TestHashChanged() {
+	code, converted, stderr := exec(s.envelopes, "convert", "--to", "msgpack")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "c5 convert: 1624140000000-")
+	assert.Contains(s.T(), stderr, "data hash changed: ")
+	assert.Equal(s.T(), 1, strings.Count(stderr, "\n"))
+	// the envelope which survives is written
+	code, back, _ := exec(converted, "convert", "--from", "msgpack", "--to", "json")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), strings.SplitAfter(s.envelopes, "\n")[0], back)
+}
+
+func (s *ConvertSuite) TestErrors() {
+	code, _, stderr := exec(s.envelopes+"[1]\n{\"v\":\"A\"}\n", "convert", "--to", "cbor")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "c5 convert: stdin:2: unexpected type: envelope is no object")
+	assert.Contains(s.T(), stderr, "c5 convert: stdin:3: missing field")
+	code, _, stderr = exec(s.envelopes+"garbage\n", "convert", "--to", "cbor")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "stdin: value 2: invalid character 'g'")
+	code, _, stderr = exec("\xff", "convert", "--from", "cbor", "--to", "json")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "c5 convert: ")
+	for _, args := range [][]string{{}, {"--to", "xml"}, {"--from", "xml", "--to", "json"}} {
+		code, _, _ = exec(s.envelopes, append([]string{"convert"}, args...)...)
+		assert.Equal(s.T(), 2, code, args)
+	}
+}
+
+func TestConvertSuite(t *testing.T) {
+	suite.Run(t, new(ConvertSuite))
+}
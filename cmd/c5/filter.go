@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// timeFlag is a time in milliseconds since 1970 or in RFC 3339.
+type timeFlag struct {
+	ms  int64
+	set bool
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+func (t *timeFlag) String() string {
+	if !t.set {
+		return ""
+	}
+	return strconv.FormatInt(t.ms, 10)
+}
+
+func (t *timeFlag) Set(v string) error {
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		t.ms, t.set = ms, true
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if tm, err := time.Parse(layout, v); err == nil {
+			t.ms, t.set = tm.UnixMilli(), true
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is no time in milliseconds or RFC 3339", v)
+}
+
+// whereList is a repeatable condition flag, commas are part of the
+// expressions.
+type whereList []*condition
+
+func (l *whereList) String() string {
+	exprs := make([]string, len(*l))
+	for idx, cond := range *l {
+		exprs[idx] = cond.expr
+	}
+	return strings.Join(exprs, " && ")
+}
+
+func (l *whereList) Set(v string) error {
+	cond, err := parseCondition(v)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, cond)
+	return nil
+}
+
+// envelopeFilter holds if all of its set criteria hold, the lists hold if
+// one of their patterns matches, see globMatch.
+type envelopeFilter struct {
+	src    stringList
+	dst    stringList
+	kind   stringList
+	since  timeFlag
+	until  timeFlag
+	minTTL int
+	maxTTL int
+	where  whereList
+}
+
+func (f *envelopeFilter) register(fs *flag.FlagSet) {
+	fs.Var(&f.src, "src", "source pattern, * matches / as well, repeatable or comma separated")
+	fs.Var(&f.dst, "dst", "pattern one of the destinations has to match")
+	fs.Var(&f.kind, "kind", "payload kind pattern")
+	fs.Var(&f.since, "since", "first t, milliseconds or RFC 3339")
+	fs.Var(&f.until, "until", "t before which the envelopes are sent")
+	fs.IntVar(&f.minTTL, "min-ttl", -1, "smallest ttl, -1 for any")
+	fs.IntVar(&f.maxTTL, "max-ttl", -1, "largest ttl, -1 for any")
+	fs.Var(&f.where, "where", "JSONPath condition on the data like '$.items[?(@.price > 10)]'\n"+
+		"or '$.name =~ ^obj', repeatable")
+}
+
+// globMatch is path.Match without the special meaning of '/': * matches
+// any characters, so svc/* matches svc/a/b as well. ? matches one
+// character and [...] a class of them, a malformed class doesn't match.
+func globMatch(pattern, value string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			pattern = strings.TrimLeft(pattern, "*")
+			if pattern == "" {
+				return true
+			}
+			for idx := range value {
+				if globMatch(pattern, value[idx:]) {
+					return true
+				}
+			}
+			return false
+		case '?', '[':
+			if value == "" {
+				return false
+			}
+			r, size := utf8.DecodeRuneInString(value)
+			if pattern[0] == '[' {
+				end := classEnd(pattern)
+				if end < 0 {
+					return false
+				}
+				if ok, err := path.Match(pattern[:end], string(r)); !ok || err != nil {
+					return false
+				}
+				pattern = pattern[end:]
+			} else {
+				pattern = pattern[1:]
+			}
+			value = value[size:]
+		default:
+			if pattern[0] == '\\' && len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			if value == "" || value[0] != pattern[0] {
+				return false
+			}
+			pattern, value = pattern[1:], value[1:]
+		}
+	}
+	return value == ""
+}
+
+// classEnd is the index behind the ] closing the class pattern starts with.
+func classEnd(pattern string) int {
+	idx := 1
+	if idx < len(pattern) && pattern[idx] == '^' {
+		idx++
+	}
+	for first := true; idx < len(pattern); idx, first = idx+1, false {
+		switch {
+		case pattern[idx] == '\\':
+			idx++
+		case pattern[idx] == ']' && !first:
+			return idx + 1
+		}
+	}
+	return -1
+}
+
+func matchAny(patterns []string, values ...string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if globMatch(pattern, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (f *envelopeFilter) match(env *c5.EnvelopeT) bool {
+	t := int64(env.T)
+	switch {
+	case !matchAny(f.src, env.Src):
+		return false
+	case !matchAny(f.dst, env.Dst...):
+		return false
+	case !matchAny(f.kind, env.Data.Kind):
+		return false
+	case f.since.set && t < f.since.ms:
+		return false
+	case f.until.set && t >= f.until.ms:
+		return false
+	case f.minTTL >= 0 && env.TTL < float64(f.minTTL):
+		return false
+	case f.maxTTL >= 0 && env.TTL > float64(f.maxTTL):
+		return false
+	}
+	for _, cond := range f.where {
+		if !cond.match(env.Data.Data) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterOutput writes the matching envelopes.
+type filterOutput interface {
+	add(in *envelopeIn) error
+	flush() error
+}
+
+// ndjsonOutput passes the envelopes through unchanged on one line each.
+type ndjsonOutput struct {
+	w io.Writer
+}
+
+func (o *ndjsonOutput) add(in *envelopeIn) error {
+	line := bytes.Buffer{}
+	if err := json.Compact(&line, in.raw); err != nil {
+		return err
+	}
+	line.WriteByte('\n')
+	_, err := o.w.Write(line.Bytes())
+	return err
+}
+
+func (o *ndjsonOutput) flush() error {
+	return nil
+}
+
+type tableOutput struct {
+	tw *tabwriter.Writer
+}
+
+func newTableOutput(w io.Writer) *tableOutput {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "T\tID\tSRC\tDST\tKIND\tTTL")
+	return &tableOutput{tw: tw}
+}
+
+func (o *tableOutput) add(in *envelopeIn) error {
+	env := in.env
+	t := time.UnixMilli(int64(env.T)).UTC().Format(c5.JSISOStringMillisFormat)
+	_, err := fmt.Fprintf(o.tw, "%s\t%s\t%s\t%s\t%s\t%v\n",
+		t, env.ID, env.Src, strings.Join(env.Dst, ","), env.Data.Kind, env.TTL)
+	return err
+}
+
+func (o *tableOutput) flush() error {
+	return o.tw.Flush()
+}
+
+// countFields are the fields countOutput groups by, an envelope counts
+// once for each of its destinations.
+var countFields = map[string]func(env *c5.EnvelopeT) []string{
+	"kind": func(env *c5.EnvelopeT) []string { return []string{env.Data.Kind} },
+	"src":  func(env *c5.EnvelopeT) []string { return []string{env.Src} },
+	"dst": func(env *c5.EnvelopeT) []string {
+		if len(env.Dst) == 0 {
+			return []string{"-"}
+		}
+		return env.Dst
+	},
+}
+
+type countOutput struct {
+	w      io.Writer
+	by     []string
+	counts map[string]int
+	groups map[string][]string
+}
+
+func newCountOutput(w io.Writer, by []string) *countOutput {
+	return &countOutput{w: w, by: by, counts: map[string]int{}, groups: map[string][]string{}}
+}
+
+func (o *countOutput) add(in *envelopeIn) error {
+	groups := [][]string{{}}
+	for _, field := range o.by {
+		next := [][]string{}
+		for _, value := range countFields[field](in.env) {
+			for _, group := range groups {
+				next = append(next, append(group[:len(group):len(group)], value))
+			}
+		}
+		groups = next
+	}
+	for _, group := range groups {
+		key := strings.Join(group, "\x00")
+		o.counts[key]++
+		o.groups[key] = group
+	}
+	return nil
+}
+
+func (o *countOutput) flush() error {
+	keys := make([]string, 0, len(o.counts))
+	for key := range o.counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tCOUNT\n", strings.ToUpper(strings.Join(o.by, "\t")))
+	for _, key := range keys {
+		fmt.Fprintf(tw, "%s\t%d\n", strings.Join(o.groups[key], "\t"), o.counts[key])
+	}
+	return tw.Flush()
+}
+
+// filter writes the matching envelopes as NDJSON.
+func (c *cli) filter(args []string) error {
+	return c.runFilter("filter", "ndjson", args)
+}
+
+// query is filter with a table as default output.
+func (c *cli) query(args []string) error {
+	return c.runFilter("query", "table", args)
+}
+
+func (c *cli) runFilter(name string, defaultOutput string, args []string) error {
+	fs := c.flags(name, "[files]")
+	filter := envelopeFilter{}
+	filter.register(fs)
+	output := fs.String("o", defaultOutput, "output ndjson, table or count")
+	by := stringList{}
+	fs.Var(&by, "by", "fields of -o count: kind, src or dst, default kind")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if len(by) == 0 {
+		by = stringList{"kind"}
+	}
+	for _, field := range by {
+		if _, found := countFields[field]; !found {
+			fmt.Fprintf(c.stderr, "c5 %s: cannot count by %s\n", name, field)
+			return errUsage
+		}
+	}
+	var out filterOutput
+	switch *output {
+	case "ndjson":
+		out = &ndjsonOutput{w: c.stdout}
+	case "table":
+		out = newTableOutput(c.stdout)
+	case "count":
+		out = newCountOutput(c.stdout, by)
+	default:
+		fmt.Fprintf(c.stderr, "c5 %s: unknown output %s\n", name, *output)
+		return errUsage
+	}
+	err := c.envelopes(fs.Args(), func(in *envelopeIn) error {
+		if in.err != nil {
+			fmt.Fprintf(c.stderr, "c5 %s: skipping %s: %v\n", name, in.name(), in.err)
+			return nil
+		}
+		if !filter.match(in.env) {
+			return nil
+		}
+		return out.add(in)
+	})
+	if err != nil {
+		return err
+	}
+	return out.flush()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FilterSuite struct {
+	suite.Suite
+	envelopes string
+}
+
+func (s *FilterSuite) SetupTest() {
+	s.envelopes = ""
+	for _, args := range [][]string{
+		{`{"name":"a","items":[{"price":5},{"price":12}]}`, "--src", "svc/a", "--dst", "x,y", "--kind", "order", "--t", "1624140000000", "--indent", "2"},
+		{`{"name":"b","items":[{"price":1}]}`, "--src", "svc/a", "--dst", "y", "--kind", "order", "--t", "1624140001000"},
+		{`{"z":1}`, "--src", "svc/b", "--kind", "ping", "--t", "1624150000000", "--ttl", "3"},
+	} {
+		_, stdout, _ := exec(args[0], append([]string{"wrap"}, args[1:]...)...)
+		s.envelopes += stdout
+	}
+}
+
+// filter returns the NDJSON lines of the output.
+func (s *FilterSuite) filter(args ...string) []string {
+	code, stdout, stderr := exec(s.envelopes, append([]string{"filter"}, args...)...)
+	assert.Equal(s.T(), 0, code, stderr)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if stdout == "" {
+		return []string{}
+	}
+	return lines
+}
+
+func (s *FilterSuite) count(args ...string) int {
+	return len(s.filter(args...))
+}
+
+func (s *FilterSuite) TestFilter() {
+	assert.Equal(s.T(), 3, s.count())
+	assert.Equal(s.T(), 2, s.count("--src", "svc/a"))
+	assert.Equal(s.T(), 3, s.count("--src", "svc/*"))
+	assert.Equal(s.T(), 2, s.count("--dst", "y"))
+	assert.Equal(s.T(), 1, s.count("--dst", "x", "--src", "svc/a"))
+	assert.Equal(s.T(), 1, s.count("--kind", "ping,nothing"))
+	assert.Equal(s.T(), 2, s.count("--since", "1624140001000"))
+	assert.Equal(s.T(), 1, s.count("--until", "2021-06-19T22:00:01Z"))
+	assert.Equal(s.T(), 1, s.count("--since", "2021-06-20"))
+	assert.Equal(s.T(), 1, s.count("--max-ttl", "5"))
+	assert.Equal(s.T(), 2, s.count("--min-ttl", "10"))
+	assert.Equal(s.T(), 1, s.count("--where", "$.items[?(@.price > 10)]"))
+	assert.Equal(s.T(), 1, s.count("--where", "$.items[*].price < 10", "--where", "name == b"))
+	assert.Equal(s.T(), 0, s.count("--where", "$.name =~ ^c"))
+}
+
+func (s *FilterSuite) TestGlob() {
+	_, nested, _ := exec(`{}`, "wrap", "--src", "svc/a/b", "--kind", "ping")
+	code, stdout, stderr := exec(s.envelopes+nested, "filter", "-o", "count", "--by", "src", "--src", "*")
+	assert.Equal(s.T(), 0, code, stderr)
+	assert.Equal(s.T(), "SRC      COUNT\nsvc/a    2\nsvc/a/b  1\nsvc/b    1\n", stdout)
+	for pattern, expected := range map[string]bool{
+		"svc/*":      true,
+		"*/b":        true,
+		"svc/?/b":    true,
+		"svc/[a-c]*": true,
+		"svc/[^a]*":  false,
+		"svc/a":      false,
+		"svc/a/b/*":  false,
+		"svc\\/a/b":  true,
+		"svc/[a":     false,
+	} {
+		assert.Equal(s.T(), expected, globMatch(pattern, "svc/a/b"), pattern)
+	}
+}
+
+func (s *FilterSuite) TestPassthrough() {
+	lines := s.filter("--where", "name == a")
+	assert.Len(s.T(), lines, 1)
+	assert.True(s.T(), strings.HasPrefix(lines[0], `{"data":{"data":{"items":[{"price":5},{"price":12}],"name":"a"}`))
+	code, stdout, _ := exec(lines[0], "verify")
+	assert.Equal(s.T(), 0, code)
+	assert.Contains(s.T(), stdout, "ok 1624140000000-")
+}
+
+func (s *FilterSuite) TestTable() {
+	code, stdout, _ := exec(s.envelopes, "query", "--kind", "ping")
+	assert.Equal(s.T(), 0, code)
+	lines := strings.Split(stdout, "\n")
+	assert.Len(s.T(), lines, 3)
+	assert.Regexp(s.T(), `^T +ID +SRC +DST +KIND +TTL$`, lines[0])
+	assert.Regexp(s.T(), `^2021-06-20T00:46:40.000Z +1624150000000-\w+ +svc/b +ping +3$`, lines[1])
+}
+
+func (s *FilterSuite) TestCount() {
+	code, stdout, _ := exec(s.envelopes, "query", "-o", "count")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), "KIND   COUNT\norder  2\nping   1\n", stdout)
+	code, stdout, _ = exec(s.envelopes, "filter", "-o", "count", "--by", "src,dst")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), "SRC    DST  COUNT\nsvc/a  x    1\nsvc/a  y    2\nsvc/b  -    1\n", stdout)
+}
+
+func (s *FilterSuite) TestErrors() {
+	code, _, stderr := exec(s.envelopes+`{"v":"A"}`, "filter", "--kind", "ping")
+	assert.Equal(s.T(), 0, code)
+	assert.Contains(s.T(), stderr, "skipping stdin:3: missing field")
+	code, _, stderr = exec(s.envelopes+"garbage\n", "filter")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "stdin: value 3: invalid character 'g'")
+	for _, args := range [][]string{
+		{"-o", "yaml"}, {"--by", "ttl"}, {"--since", "yesterday"}, {"--where", "$.items["},
+	} {
+		code, _, _ = exec(s.envelopes, append([]string{"query"}, args...)...)
+		assert.Equal(s.T(), 2, code, args)
+	}
+}
+
+func TestFilterSuite(t *testing.T) {
+	suite.Run(t, new(FilterSuite))
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// format reads and writes JSON values in one wire format. The values are
+// dicts like decodeJson returns them: json.Number, string, bool, nil,
+// map[string]interface{} and []interface{}.
+type format struct {
+	decode func(r io.Reader, fn func(v interface{}) error) error
+	// encode writes one value including its separator
+	encode func(w io.Writer, v interface{}) error
+}
+
+var formats = map[string]format{
+	"json":        {decode: decodeJsonStream, encode: encodeJson("")},
+	"json-indent": {decode: decodeJsonStream, encode: encodeJson("  ")},
+	"cbor":        {decode: decodeCbor, encode: encodeCbor},
+	"msgpack":     {decode: decodeMsgpack, encode: encodeMsgpack},
+	"yaml":        {decode: decodeYaml, encode: encodeYaml},
+}
+
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func decodeJsonStream(r io.Reader, fn func(v interface{}) error) error {
+	return jsonValues(r, func(raw json.RawMessage) error {
+		var v interface{}
+		if err := decodeJson(raw, &v); err != nil {
+			return err
+		}
+		return fn(v)
+	})
+}
+
+func encodeJson(indent string) func(w io.Writer, v interface{}) error {
+	return func(w io.Writer, v interface{}) error {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", indent)
+		return enc.Encode(v)
+	}
+}
+
+// wireValue converts the json.Numbers of a dict to the integer or float
+// types of the binary formats, integers beyond 64 bit become *big.Int if
+// bigInts is set and float64 otherwise.
+func wireValue(v interface{}, bigInts bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			ret[key] = wireValue(item, bigInts)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for idx, item := range val {
+			ret[idx] = wireValue(item, bigInts)
+		}
+		return ret
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if i, ok := new(big.Int).SetString(string(val), 10); ok {
+			if i.IsUint64() {
+				return i.Uint64()
+			}
+			if bigInts {
+				return i
+			}
+		}
+		f, _ := val.Float64()
+		return f
+	}
+	return v
+}
+
+// dictValue converts a decoded value of any format to a dict value, the
+// numbers become their canonical json.Number.
+func dictValue(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case nil, string, bool:
+		return val, nil
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			conv, err := dictValue(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[key] = conv
+		}
+		return ret, nil
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(val))
+		for key, item := range val {
+			str, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("%T map key %v is no string", key, key)
+			}
+			conv, err := dictValue(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[str] = conv
+		}
+		return ret, nil
+	case []interface{}:
+		ret := make([]interface{}, len(val))
+		for idx, item := range val {
+			conv, err := dictValue(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[idx] = conv
+		}
+		return ret, nil
+	case big.Int:
+		return json.Number(val.String()), nil
+	case []byte:
+		return nil, fmt.Errorf("byte string %x has no JSON form", val)
+	}
+	if num, ok := c5.CanonicalNumber(v); ok {
+		return num, nil
+	}
+	if arr, err := c5.AsArray(v); err == nil {
+		return dictValue(arr)
+	}
+	return nil, fmt.Errorf("%T %v has no JSON form", v, v)
+}
+
+func decodeCbor(r io.Reader, fn func(v interface{}) error) error {
+	dec := cbor.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
+
+// cborMode is the core deterministic encoding of RFC 8949, the values
+// follow each other as CBOR sequence.
+var cborMode, _ = cbor.CoreDetEncOptions().EncMode()
+
+func encodeCbor(w io.Writer, v interface{}) error {
+	return cborMode.NewEncoder(w).Encode(wireValue(v, true))
+}
+
+func decodeMsgpack(r io.Reader, fn func(v interface{}) error) error {
+	dec := msgpack.NewDecoder(r)
+	for {
+		var v interface{}
+		err := dec.Decode(&v)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
+
+func encodeMsgpack(w io.Writer, v interface{}) error {
+	enc := msgpack.NewEncoder(w)
+	enc.SetSortMapKeys(true)
+	return enc.Encode(wireValue(v, false))
+}
+
+// decodeYaml reads a stream of documents, the scalars keep their text so
+// integers of any size stay exact. Unquoted timestamps are strings like in
+// JSON.
+func decodeYaml(r io.Reader, fn func(v interface{}) error) error {
+	dec := yaml.NewDecoder(r)
+	for {
+		doc := yaml.Node{}
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		v, err := yamlValue(&doc)
+		if err != nil {
+			return err
+		}
+		if err := fn(v); err != nil {
+			return err
+		}
+	}
+}
+
+func yamlValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlValue(node.Alias)
+	case yaml.MappingNode:
+		ret := make(map[string]interface{}, len(node.Content)/2)
+		for idx := 0; idx+1 < len(node.Content); idx += 2 {
+			key := node.Content[idx]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: map key is no scalar", key.Line)
+			}
+			val, err := yamlValue(node.Content[idx+1])
+			if err != nil {
+				return nil, err
+			}
+			ret[key.Value] = val
+		}
+		return ret, nil
+	case yaml.SequenceNode:
+		ret := make([]interface{}, len(node.Content))
+		for idx, item := range node.Content {
+			val, err := yamlValue(item)
+			if err != nil {
+				return nil, err
+			}
+			ret[idx] = val
+		}
+		return ret, nil
+	}
+	switch node.ShortTag() {
+	case "!!str", "!!timestamp":
+		return node.Value, nil
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		b := false
+		err := node.Decode(&b)
+		return b, err
+	case "!!int":
+		if i, ok := new(big.Int).SetString(node.Value, 0); ok {
+			return json.Number(i.String()), nil
+		}
+		return nil, fmt.Errorf("line %d: invalid integer %s", node.Line, node.Value)
+	case "!!float":
+		f := 0.0
+		if err := node.Decode(&f); err != nil {
+			return nil, err
+		}
+		return dictValue(f)
+	}
+	return nil, fmt.Errorf("line %d: %s has no JSON form", node.Line, node.ShortTag())
+}
+
+// yamlNode renders strings always as strings and numbers with their
+// digits.
+func yamlNode(v interface{}) *yaml.Node {
+	scalar := func(tag string, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for key := range val {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, key := range keys {
+			node.Content = append(node.Content, scalar("!!str", key), yamlNode(val[key]))
+		}
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, item := range val {
+			node.Content = append(node.Content, yamlNode(item))
+		}
+		return node
+	case string:
+		return scalar("!!str", val)
+	case bool:
+		if val {
+			return scalar("!!bool", "true")
+		}
+		return scalar("!!bool", "false")
+	case json.Number:
+		if _, ok := new(big.Int).SetString(string(val), 10); ok {
+			return scalar("!!int", string(val))
+		}
+		return scalar("!!float", string(val))
+	}
+	return scalar("!!null", "null")
+}
+
+// encodeYaml starts every document with "---", so the output of several
+// envelopes is one stream.
+func encodeYaml(w io.Writer, v interface{}) error {
+	buf := bytes.Buffer{}
+	buf.WriteString("---\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(yamlNode(v)); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FormatsSuite struct {
+	suite.Suite
+}
+
+func (s *FormatsSuite) dict() interface{} {
+	var v interface{}
+	assert.NoError(s.T(), decodeJson([]byte(`{
+		"s": "2021-05-20", "q": "true", "e": "", "b": false, "n": null,
+		"i": -3, "u": 18446744073709551615, "big": 123456789012345678901234567890,
+		"f": 1.5, "tiny": 1e-7, "huge": 1e300,
+		"l": [1, "1", [], {}], "o": {"x": {"y": [null]}}
+	}`), &v))
+	val, err := dictValue(v)
+	assert.NoError(s.T(), err)
+	return val
+}
+
+func (s *FormatsSuite) roundTrip(name string, v interface{}) []interface{} {
+	buf := bytes.Buffer{}
+	assert.NoError(s.T(), formats[name].encode(&buf, v))
+	assert.NoError(s.T(), formats[name].encode(&buf, v))
+	ret := []interface{}{}
+	assert.NoError(s.T(), formats[name].decode(&buf, func(v interface{}) error {
+		val, err := dictValue(v)
+		ret = append(ret, val)
+		return err
+	}))
+	return ret
+}
+
+func (s *FormatsSuite) TestRoundTrip() {
+	dict := s.dict()
+	for _, name := range []string{"json", "json-indent", "cbor", "yaml"} {
+		assert.Equal(s.T(), []interface{}{dict, dict}, s.roundTrip(name, dict), name)
+	}
+	back := s.roundTrip("msgpack", dict)
+	assert.Len(s.T(), back, 2)
+	// msgpack has no integers beyond 64 bit
+	assert.Equal(s.T(), json.Number("1.2345678901234568e+29"), back[0].(map[string]interface{})["big"])
+	delete(back[0].(map[string]interface{}), "big")
+	delete(dict.(map[string]interface{}), "big")
+	assert.Equal(s.T(), dict, back[0])
+}
+
+func (s *FormatsSuite) TestDictValue() {
+	val, err := dictValue(map[interface{}]interface{}{"a": []string{"b"}, "n": uint8(7), "f": float32(0.1), "i": *big.NewInt(-1)})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), map[string]interface{}{
+		"a": []interface{}{"b"}, "n": json.Number("7"), "f": json.Number("0.1"), "i": json.Number("-1"),
+	}, val)
+	for _, v := range []interface{}{
+		map[interface{}]interface{}{1: "a"}, []byte("a"), math.NaN(), math.Inf(1), struct{}{},
+		[]interface{}{cbor.Tag{Number: 100, Content: 1}},
+	} {
+		_, err := dictValue(v)
+		assert.Error(s.T(), err, v)
+	}
+}
+
+func (s *FormatsSuite) TestYaml() {
+	var ret interface{}
+	err := decodeYaml(strings.NewReader("d: 2021-05-20\nx: 0x1F\nbig: 1_000\nf: 1.0\nyes: true\nn: ~\na: &a [1]\nb: *a\n"), func(v interface{}) error {
+		ret = v
+		return nil
+	})
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), map[string]interface{}{
+		"d": "2021-05-20", "x": json.Number("31"), "big": json.Number("1000"), "f": json.Number("1"),
+		"yes": true, "n": nil, "a": []interface{}{json.Number("1")}, "b": []interface{}{json.Number("1")},
+	}, ret)
+	for _, doc := range []string{"? [1]\n: 2\n", "f: .inf\n", "b: !!binary aGk=\n", "a: [\n"} {
+		err := decodeYaml(strings.NewReader(doc), func(v interface{}) error { return nil })
+		assert.Error(s.T(), err, doc)
+	}
+}
+
+func (s *FormatsSuite) TestCborDeterministic() {
+	buf := bytes.Buffer{}
+	assert.NoError(s.T(), encodeCbor(&buf, map[string]interface{}{"bb": json.Number("1"), "a": json.Number("1.5")}))
+	// shortest keys first, 1.5 as half precision float
+	assert.Equal(s.T(), []byte{0xa2, 0x61, 'a', 0xf9, 0x3e, 0x00, 0x62, 'b', 'b', 0x01}, buf.Bytes())
+}
+
+func TestFormatsSuite(t *testing.T) {
+	suite.Run(t, new(FormatsSuite))
+}
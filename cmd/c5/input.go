@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// errUsage exits with 2, the flag set printed the problem already.
+var errUsage = errors.New("usage")
+
+func parse(fs *flag.FlagSet, args []string) error {
+	err := fs.Parse(args)
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		return errUsage
+	}
+	return err
+}
+
+// stringList is a repeatable flag which also splits at commas.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// decodeJson decodes the numbers as json.Number, so they keep their
+// canonical form and integers above 2^53 are exact.
+func decodeJson(raw []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// inputs passes the named files or stdin for none or "-" to fn.
+func (c *cli) inputs(fnames []string, fn func(name string, r io.Reader) error) error {
+	if len(fnames) == 0 {
+		fnames = []string{"-"}
+	}
+	for _, fname := range fnames {
+		if fname == "-" {
+			if err := fn("stdin", c.stdin); err != nil {
+				return err
+			}
+			continue
+		}
+		file, err := os.Open(fname)
+		if err != nil {
+			return err
+		}
+		err = fn(fname, file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonValues passes every JSON value of r to fn, the values can be
+// separated by newlines like NDJSON or be indented. A malformed value
+// stops the stream, the error names the value by its index.
+func jsonValues(r io.Reader, fn func(raw json.RawMessage) error) error {
+	dec := json.NewDecoder(r)
+	for idx := 0; ; idx++ {
+		raw := json.RawMessage{}
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("value %d: %w", idx, err)
+		}
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+}
+
+// envelopeIn is one envelope of the input, err is set if it could not be
+// decoded as EnvelopeT.
+type envelopeIn struct {
+	source string
+	idx    int
+	raw    json.RawMessage
+	env    *c5.EnvelopeT
+	err    error
+}
+
+func (e *envelopeIn) name() string {
+	if e.env != nil && e.env.ID != "" {
+		return e.env.ID
+	}
+	return fmt.Sprintf("%s:%d", e.source, e.idx)
+}
+
+// envelopes decodes the envelopes of the inputs, fn decides about
+// envelopes which are no valid EnvelopeT.
+func (c *cli) envelopes(fnames []string, fn func(in *envelopeIn) error) error {
+	return c.inputs(fnames, func(name string, r io.Reader) error {
+		idx := 0
+		var fnErr error
+		err := jsonValues(r, func(raw json.RawMessage) error {
+			in := &envelopeIn{source: name, idx: idx, raw: raw}
+			idx++
+			dict := map[string]interface{}{}
+			in.err = decodeJson(raw, &dict)
+			if in.err == nil {
+				env := c5.EnvelopeT{}
+				in.err = c5.FromDictEnvelopeT(dict, &env)
+				in.env = &env
+			}
+			fnErr = fn(in)
+			return fnErr
+		})
+		if err != nil && err != fnErr {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// inspect prints the header fields, the data and whether the id matches
+// the data hash.
+func (c *cli) inspect(args []string) error {
+	fs := c.flags("inspect", "[files]")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	first := true
+	return c.envelopes(fs.Args(), func(in *envelopeIn) error {
+		if !first {
+			fmt.Fprintln(c.stdout)
+		}
+		first = false
+		if in.err != nil {
+			fmt.Fprintf(c.stdout, "%s: invalid envelope: %v\n", in.name(), in.err)
+			return nil
+		}
+		env := in.env
+		tw := tabwriter.NewWriter(c.stdout, 0, 4, 1, ' ', 0)
+		fmt.Fprintf(tw, "id:\t%s\n", env.ID)
+		fmt.Fprintf(tw, "v:\t%s\n", c5.ToV(env.V))
+		fmt.Fprintf(tw, "src:\t%s\n", env.Src)
+		fmt.Fprintf(tw, "dst:\t%s\n", strings.Join(env.Dst, ", "))
+		t := time.UnixMilli(int64(env.T)).UTC()
+		fmt.Fprintf(tw, "t:\t%d (%s)\n", int64(env.T), t.Format(c5.JSISOStringMillisFormat))
+		fmt.Fprintf(tw, "ttl:\t%v\n", env.TTL)
+		fmt.Fprintf(tw, "kind:\t%s\n", env.Data.Kind)
+		hash := c5.NewSimpleEnvelopeFromEnvelopeT(env, nil).DataHash()
+		check := "ok"
+		if err := c5.VerifyID(env); err != nil {
+			check = "MISMATCH"
+		}
+		fmt.Fprintf(tw, "hash:\t%s %s\n", hash, check)
+		for idx, sig := range env.Signatures {
+			fmt.Fprintf(tw, "signature %d:\t%s %s by %s at %s\n", idx, sig.Alg, sig.Kid, sig.Src,
+				time.UnixMilli(int64(sig.T)).UTC().Format(c5.JSISOStringMillisFormat))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		data, err := json.MarshalIndent(env.Data.Data, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(c.stdout, "data:\n%s\n", data)
+		return err
+	})
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type InspectSuite struct {
+	suite.Suite
+}
+
+func (s *InspectSuite) TestInspect() {
+	_, envelope, _ := exec(`{"name":"object","date":"2021-05-20"}`,
+		"wrap", "--src", "test case", "--dst", "a,b", "--kind", "test", "--t", "1624140000000", "--indent", "2")
+	code, stdout, _ := exec(envelope, "inspect")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), `id:   1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp
+v:    A
+src:  test case
+dst:  a, b
+t:    1624140000000 (2021-06-19T22:00:00.000Z)
+ttl:  10
+kind: test
+hash: BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp ok
+data:
+{
+  "date": "2021-05-20",
+  "name": "object"
+}
+`, stdout)
+
+	code, stdout, _ = exec(strings.Replace(envelope, "object", "objekt", 1)+`{"v":"B"}`, "inspect")
+	assert.Equal(s.T(), 0, code)
+	assert.Contains(s.T(), stdout, "hash: 8sWu3odNzeAEBjNJPSpJA5azHP9doC82ifDyyphA5GWx MISMATCH")
+	assert.Contains(s.T(), stdout, "\n\nstdin:1: invalid envelope")
+}
+
+func TestInspectSuite(t *testing.T) {
+	suite.Run(t, new(InspectSuite))
+}
@@ -0,0 +1,445 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// jsonPath selects values from the data of a payload with a subset of
+// JSONPath, $ is the data object:
+//
+//	$.name  $['name']  $.items[0]  $.items[-1]  $.items[*]  $.*  $..name
+//	$.items[?(@.price > 10)]
+//
+// The leading "$." may be left out, "items.0.sku" addresses array elements
+// like the data paths of the envelope package.
+type jsonPath []pathStep
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepWildcard
+	stepFilter
+)
+
+type pathStep struct {
+	kind  stepKind
+	key   string
+	index int
+	cond  *condition
+	// recursive applies the step to the node and all its descendants
+	recursive bool
+}
+
+// condition compares the values a path selects with a literal and holds if
+// one of them matches. Without an operator it holds if the path selects
+// anything.
+type condition struct {
+	expr  string
+	path  jsonPath
+	op    string
+	value interface{}
+	re    *regexp.Regexp
+}
+
+// the longer operators first
+var conditionOps = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+// parseCondition parses "path", "path op value" or "path =~ regexp", the
+// value is a JSON literal or a bare string.
+func parseCondition(expr string) (*condition, error) {
+	p := &pathParser{src: expr}
+	p.skipSpaces()
+	cond, err := p.condition('$', 0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	return cond, nil
+}
+
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q at %d: %s", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *pathParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *pathParser) skipSpaces() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || c == '-' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// path parses the steps up to the first character which does not belong
+// to a path, root is $ or @ in filters.
+func (p *pathParser) path(root byte) (jsonPath, error) {
+	ret := jsonPath{}
+	if p.peek() == root {
+		p.pos++
+	} else if isNameChar(p.peek()) {
+		step, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, step)
+	}
+	for {
+		var step pathStep
+		var err error
+		switch p.peek() {
+		case '.':
+			p.pos++
+			recursive := false
+			if p.peek() == '.' {
+				recursive = true
+				p.pos++
+			}
+			switch {
+			case p.peek() == '[' && recursive:
+				step, err = p.bracket()
+			case p.peek() == '*':
+				p.pos++
+				step = pathStep{kind: stepWildcard}
+			default:
+				step, err = p.name()
+			}
+			step.recursive = recursive
+		case '[':
+			step, err = p.bracket()
+		default:
+			return ret, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, step)
+	}
+}
+
+func (p *pathParser) name() (pathStep, error) {
+	start := p.pos
+	for isNameChar(p.peek()) {
+		p.pos++
+	}
+	if start == p.pos {
+		return pathStep{}, p.errorf("name expected")
+	}
+	return pathStep{kind: stepKey, key: p.src[start:p.pos]}, nil
+}
+
+func (p *pathParser) bracket() (pathStep, error) {
+	p.pos++
+	p.skipSpaces()
+	var step pathStep
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		step = pathStep{kind: stepWildcard}
+	case c == '\'' || c == '"':
+		key, err := p.quoted()
+		if err != nil {
+			return step, err
+		}
+		step = pathStep{kind: stepKey, key: key}
+	case c == '?':
+		p.pos++
+		if p.peek() != '(' {
+			return step, p.errorf("( expected")
+		}
+		p.pos++
+		p.skipSpaces()
+		cond, err := p.condition('@', ')')
+		if err != nil {
+			return step, err
+		}
+		if p.peek() != ')' {
+			return step, p.errorf(") expected")
+		}
+		p.pos++
+		step = pathStep{kind: stepFilter, cond: cond}
+	default:
+		start := p.pos
+		for c := p.peek(); c == '-' || c >= '0' && c <= '9'; c = p.peek() {
+			p.pos++
+		}
+		idx, err := strconv.Atoi(p.src[start:p.pos])
+		if err != nil {
+			p.pos = start
+			return step, p.errorf("index, name or filter expected")
+		}
+		step = pathStep{kind: stepIndex, index: idx}
+	}
+	p.skipSpaces()
+	if p.peek() != ']' {
+		return step, p.errorf("] expected")
+	}
+	p.pos++
+	return step, nil
+}
+
+// quoted reads a string in single or double quotes, a backslash escapes
+// the next character.
+func (p *pathParser) quoted() (string, error) {
+	quote := p.peek()
+	p.pos++
+	sb := strings.Builder{}
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			sb.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+// condition parses up to end, 0 is the end of the expression.
+func (p *pathParser) condition(root byte, end byte) (*condition, error) {
+	start := p.pos
+	path, err := p.path(root)
+	if err != nil {
+		return nil, err
+	}
+	cond := &condition{path: path}
+	p.skipSpaces()
+	if p.pos == len(p.src) || end != 0 && p.peek() == end {
+		cond.expr = strings.TrimSpace(p.src[start:p.pos])
+		return cond, nil
+	}
+	for _, op := range conditionOps {
+		if strings.HasPrefix(p.src[p.pos:], op) {
+			cond.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if cond.op == "" {
+		return nil, p.errorf("operator expected")
+	}
+	p.skipSpaces()
+	if c := p.peek(); c == '\'' || c == '"' {
+		cond.value, err = p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+	} else {
+		valStart := p.pos
+		for p.pos < len(p.src) && (end == 0 || p.peek() != end) {
+			p.pos++
+		}
+		text := strings.TrimSpace(p.src[valStart:p.pos])
+		if text == "" {
+			return nil, p.errorf("value expected")
+		}
+		cond.value = text
+		if json.Valid([]byte(text)) {
+			if err := decodeJson([]byte(text), &cond.value); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+		}
+	}
+	if cond.op == "=~" {
+		str, ok := cond.value.(string)
+		if !ok {
+			return nil, p.errorf("=~ needs a regular expression")
+		}
+		cond.re, err = regexp.Compile(str)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+	}
+	cond.expr = strings.TrimSpace(p.src[start:p.pos])
+	return cond, nil
+}
+
+// selectFrom returns the values the path selects in root.
+func (jp jsonPath) selectFrom(root interface{}) []interface{} {
+	nodes := []interface{}{root}
+	for _, step := range jp {
+		next := []interface{}{}
+		for _, node := range nodes {
+			if !step.recursive {
+				next = step.apply(node, next)
+				continue
+			}
+			for _, desc := range descendants(node) {
+				next = step.apply(desc, next)
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (s pathStep) apply(node interface{}, ret []interface{}) []interface{} {
+	switch s.kind {
+	case stepKey:
+		switch v := node.(type) {
+		case map[string]interface{}:
+			if val, found := v[s.key]; found {
+				ret = append(ret, val)
+			}
+		case []interface{}:
+			if idx, err := strconv.Atoi(s.key); err == nil && idx >= 0 && idx < len(v) {
+				ret = append(ret, v[idx])
+			}
+		}
+	case stepIndex:
+		if v, ok := node.([]interface{}); ok {
+			idx := s.index
+			if idx < 0 {
+				idx += len(v)
+			}
+			if idx >= 0 && idx < len(v) {
+				ret = append(ret, v[idx])
+			}
+		}
+	case stepWildcard:
+		ret = append(ret, children(node)...)
+	case stepFilter:
+		for _, child := range children(node) {
+			if s.cond.match(child) {
+				ret = append(ret, child)
+			}
+		}
+	}
+	return ret
+}
+
+// children are the attribute values sorted by name or the elements.
+func children(node interface{}) []interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		ret := make([]interface{}, len(keys))
+		for idx, key := range keys {
+			ret[idx] = v[key]
+		}
+		return ret
+	case []interface{}:
+		return v
+	}
+	return nil
+}
+
+// descendants are node and everything below in document order.
+func descendants(node interface{}) []interface{} {
+	ret := []interface{}{node}
+	for _, child := range children(node) {
+		ret = append(ret, descendants(child)...)
+	}
+	return ret
+}
+
+func (c *condition) match(node interface{}) bool {
+	for _, val := range c.path.selectFrom(node) {
+		if c.op == "" || c.compare(val) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *condition) compare(val interface{}) bool {
+	switch c.op {
+	case "=~":
+		str, ok := val.(string)
+		return ok && c.re.MatchString(str)
+	case "==":
+		return equal(val, c.value)
+	case "!=":
+		return !equal(val, c.value)
+	}
+	cmp, ok := order(val, c.value)
+	if !ok {
+		return false
+	}
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp >= 0
+}
+
+// number parses json.Number exactly, 1.0 equals 1 and integers above 2^53
+// keep their digits.
+func number(v interface{}) (*big.Float, bool) {
+	var str string
+	switch n := v.(type) {
+	case json.Number:
+		str = string(n)
+	case float64:
+		str = strconv.FormatFloat(n, 'g', -1, 64)
+	default:
+		return nil, false
+	}
+	f, _, err := big.ParseFloat(str, 10, 256, big.ToNearestEven)
+	return f, err == nil
+}
+
+// order compares two numbers or two strings.
+func order(a interface{}, b interface{}) (int, bool) {
+	if na, ok := number(a); ok {
+		if nb, ok := number(b); ok {
+			return na.Cmp(nb), true
+		}
+		return 0, false
+	}
+	sa, ok := a.(string)
+	if !ok {
+		return 0, false
+	}
+	sb, ok := b.(string)
+	if !ok {
+		return 0, false
+	}
+	return strings.Compare(sa, sb), true
+}
+
+func equal(a interface{}, b interface{}) bool {
+	if cmp, ok := order(a, b); ok {
+		return cmp == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
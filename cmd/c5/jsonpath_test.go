@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JsonPathSuite struct {
+	suite.Suite
+	data interface{}
+}
+
+func (s *JsonPathSuite) SetupTest() {
+	s.data = nil
+	assert.NoError(s.T(), decodeJson([]byte(`{
+		"name": "order",
+		"customer": {"email": "a@b.c", "tags": ["vip", "new"]},
+		"items": [
+			{"sku": "x-1", "price": 5, "qty": 1},
+			{"sku": "y-2", "price": 12.50, "qty": 18446744073709551617}
+		],
+		"odd key": true,
+		"none": null
+	}`), &s.data))
+}
+
+func (s *JsonPathSuite) selectFrom(expr string) []interface{} {
+	cond, err := parseCondition(expr)
+	assert.NoError(s.T(), err)
+	return cond.path.selectFrom(s.data)
+}
+
+func (s *JsonPathSuite) TestSelect() {
+	assert.Equal(s.T(), []interface{}{"order"}, s.selectFrom("$.name"))
+	assert.Equal(s.T(), []interface{}{"order"}, s.selectFrom("name"))
+	assert.Equal(s.T(), []interface{}{"a@b.c"}, s.selectFrom("$['customer'][\"email\"]"))
+	assert.Equal(s.T(), []interface{}{"new"}, s.selectFrom("$.customer.tags[-1]"))
+	assert.Equal(s.T(), []interface{}{"vip"}, s.selectFrom("customer.tags.0"))
+	assert.Equal(s.T(), []interface{}{"x-1", "y-2"}, s.selectFrom("$.items[*].sku"))
+	assert.Equal(s.T(), []interface{}{"x-1", "y-2"}, s.selectFrom("$..sku"))
+	assert.Equal(s.T(), []interface{}{true}, s.selectFrom("$['odd key']"))
+	assert.Equal(s.T(), []interface{}{nil}, s.selectFrom("$.none"))
+	assert.Len(s.T(), s.selectFrom("$.*"), 5)
+	assert.Empty(s.T(), s.selectFrom("$.items[2]"))
+	assert.Empty(s.T(), s.selectFrom("$.name.first"))
+	assert.Equal(s.T(), []interface{}{"y-2"}, s.selectFrom("$.items[?(@.price > 10)].sku"))
+	assert.Equal(s.T(), []interface{}{"x-1"}, s.selectFrom("$..[?(@.sku =~ '^x')].sku"))
+}
+
+func (s *JsonPathSuite) match(expr string) bool {
+	cond, err := parseCondition(expr)
+	assert.NoError(s.T(), err, expr)
+	return cond.match(s.data)
+}
+
+func (s *JsonPathSuite) TestMatch() {
+	assert.True(s.T(), s.match("$.customer.email"))
+	assert.False(s.T(), s.match("$.customer.phone"))
+	assert.True(s.T(), s.match("$.name == order"))
+	assert.True(s.T(), s.match(`$.name == "order"`))
+	assert.True(s.T(), s.match("$.name != invoice"))
+	assert.True(s.T(), s.match("$.name < p"))
+	assert.True(s.T(), s.match("$.items[1].price == 12.5"))
+	assert.True(s.T(), s.match("$.items[*].price >= 12.5"))
+	assert.False(s.T(), s.match("$.items[*].price > 12.5"))
+	assert.True(s.T(), s.match("$.items[1].qty > 18446744073709551616"))
+	assert.False(s.T(), s.match("$.items[1].qty == 18446744073709551616"))
+	assert.False(s.T(), s.match("$.name > 1"))
+	assert.True(s.T(), s.match("$['odd key'] == true"))
+	assert.True(s.T(), s.match("$.none == null"))
+	assert.True(s.T(), s.match("$.customer.tags[*] =~ ^v"))
+	assert.True(s.T(), s.match("$.items[?(@.qty)]"))
+	assert.True(s.T(), s.match(`$.items[?(@.sku == "y-2")].price == 12.5`))
+}
+
+func (s *JsonPathSuite) TestNumbers() {
+	assert.True(s.T(), equal(json.Number("1.0"), json.Number("1")))
+	assert.True(s.T(), equal(json.Number("1e2"), float64(100)))
+	assert.False(s.T(), equal(json.Number("1"), "1"))
+	_, ok := order(true, true)
+	assert.False(s.T(), ok)
+}
+
+func (s *JsonPathSuite) TestErrors() {
+	for _, expr := range []string{"$.", "$[", "$['a", "$[x]", "$.a ==", "$.a ~ 1",
+		"$.a =~ 1", "$.a =~ (", "$[?(@.a]", "$[?@.a]", "$.a b"} {
+		_, err := parseCondition(expr)
+		assert.Error(s.T(), err, expr)
+	}
+}
+
+func TestJsonPathSuite(t *testing.T) {
+	suite.Run(t, new(JsonPathSuite))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+var keyAlgs = map[string]string{
+	"ed25519": c5.AlgEdDSA,
+	"eddsa":   c5.AlgEdDSA,
+	"es256":   c5.AlgES256,
+	"es384":   c5.AlgES384,
+	"rs256":   c5.AlgRS256,
+}
+
+func marshalKey(key *c5.Key, format string, withPrivate bool) ([]byte, error) {
+	if format == "pem" {
+		return key.MarshalPEM(withPrivate)
+	}
+	out, err := key.MarshalJWK(withPrivate)
+	return append(out, '\n'), err
+}
+
+// keygen creates a key pair bound to src. The private key is written to
+// stdout or -o, then stdout gets the key id.
+func (c *cli) keygen(args []string) error {
+	fs := c.flags("keygen", "")
+	alg := fs.String("alg", "ed25519", "ed25519, es256, es384 or rs256")
+	src := fs.String("src", "", "source the key signs for")
+	format := fs.String("format", "jwk", "jwk or pem")
+	out := fs.String("o", "", "file of the private key, default stdout")
+	pub := fs.String("pub", "", "file of the public key")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	keyAlg, found := keyAlgs[strings.ToLower(*alg)]
+	if *src == "" || !found || (*format != "jwk" && *format != "pem") || fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+	key, err := c5.GenerateKey(keyAlg, *src)
+	if err != nil {
+		return err
+	}
+	private, err := marshalKey(key, *format, true)
+	if err != nil {
+		return err
+	}
+	if *pub != "" {
+		public, err := marshalKey(key, *format, false)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(*pub, public, 0644); err != nil {
+			return err
+		}
+	}
+	if *out == "" {
+		_, err = c.stdout.Write(private)
+		return err
+	}
+	if err := os.WriteFile(*out, private, 0600); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(c.stdout, key.ID)
+	return err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+type KeygenSuite struct {
+	suite.Suite
+}
+
+func (s *KeygenSuite) TestStdout() {
+	for alg, expected := range map[string]string{"ed25519": c5.AlgEdDSA, "ES256": c5.AlgES256, "es384": c5.AlgES384} {
+		code, stdout, _ := exec("", "keygen", "--src", "me", "--alg", alg)
+		assert.Equal(s.T(), 0, code)
+		keys, err := c5.ParseJWK([]byte(stdout))
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), keys, 1)
+		assert.Equal(s.T(), expected, keys[0].Alg)
+		assert.Equal(s.T(), "me", keys[0].Src)
+		assert.NotNil(s.T(), keys[0].Private)
+	}
+}
+
+func (s *KeygenSuite) TestFiles() {
+	dir := s.T().TempDir()
+	private := filepath.Join(dir, "me.pem")
+	public := filepath.Join(dir, "me.pub.pem")
+	code, stdout, _ := exec("", "keygen", "--src", "me", "--format", "pem", "-o", private, "--pub", public)
+	assert.Equal(s.T(), 0, code)
+	kid := strings.TrimSpace(stdout)
+	keys, err := c5.LoadKeyFile(private)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), kid, keys[0].ID)
+	assert.NotNil(s.T(), keys[0].Private)
+	info, err := os.Stat(private)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), os.FileMode(0600), info.Mode().Perm())
+	keys, err = c5.LoadKeyFile(public)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), kid, keys[0].ID)
+	assert.Nil(s.T(), keys[0].Private)
+}
+
+func (s *KeygenSuite) TestErrors() {
+	for _, args := range [][]string{{}, {"--src", "me", "--alg", "dsa"}, {"--src", "me", "--format", "der"}, {"--src", "me", "x"}} {
+		code, _, _ := exec("", append([]string{"keygen"}, args...)...)
+		assert.Equal(s.T(), 2, code, args)
+	}
+	code, _, _ := exec("", "keygen", "--src", "me", "-o", filepath.Join(s.T().TempDir(), "missing", "me.jwk"))
+	assert.Equal(s.T(), 1, code)
+}
+
+func TestKeygenSuite(t *testing.T) {
+	suite.Run(t, new(KeygenSuite))
+}
@@ -0,0 +1,94 @@
+// c5 creates and checks envelopes in shell scripts.
+//
+//	echo '{"name":"object"}' | c5 wrap --src me --kind test | c5 verify
+//	c5 query --kind "c5.*" --where '$.items[?(@.price > 10)]' captures.ndjson
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// errFailed exits with 1 after the command reported the failure itself.
+var errFailed = errors.New("failed")
+
+// cli are the streams of one invocation.
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type command struct {
+	usage string
+	run   func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"wrap":    {usage: "wraps the JSON data on stdin into envelopes", run: (*cli).wrap},
+	"inspect": {usage: "prints envelopes with decoded time and hash check", run: (*cli).inspect},
+	"verify":  {usage: "checks ids against the data hash and signatures against keys", run: (*cli).verify},
+	"filter":  {usage: "prints the envelopes which match as NDJSON", run: (*cli).filter},
+	"query":   {usage: "filter printing a table or counts", run: (*cli).query},
+	"convert": {usage: "converts envelopes between JSON, CBOR, MessagePack and YAML", run: (*cli).convert},
+	"keygen":  {usage: "creates an Ed25519 or ECDSA key pair as JWK or PEM", run: (*cli).keygen},
+	"sign":    {usage: "signs envelopes in place, as stream or detached", run: (*cli).sign},
+}
+
+func (c *cli) usage() {
+	fmt.Fprintln(c.stderr, "usage: c5 <command> [flags] [files]")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.stderr, "  %-8s %s\n", name, commands[name].usage)
+	}
+}
+
+// flags returns a flag set which reports errors to stderr.
+func (c *cli) flags(name string, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "usage: c5 %s [flags] %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// run returns the exit code, 2 for usage errors.
+func (c *cli) run(args []string) int {
+	if len(args) == 0 {
+		c.usage()
+		return 2
+	}
+	cmd, found := commands[args[0]]
+	if !found {
+		fmt.Fprintf(c.stderr, "c5: unknown command %s\n", args[0])
+		c.usage()
+		return 2
+	}
+	err := cmd.run(c, args[1:])
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 2
+	case errors.Is(err, errUsage):
+		return 2
+	case !errors.Is(err, errFailed):
+		fmt.Fprintf(c.stderr, "c5 %s: %v\n", args[0], err)
+	}
+	return 1
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	os.Exit(c.run(os.Args[1:]))
+}
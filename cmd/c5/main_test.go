@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// exec runs the command line with stdin and returns the exit code and the
+// output.
+func exec(stdin string, args ...string) (int, string, string) {
+	stdout := bytes.Buffer{}
+	stderr := bytes.Buffer{}
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	code := c.run(args)
+	return code, stdout.String(), stderr.String()
+}
+
+type MainSuite struct {
+	suite.Suite
+}
+
+func (s *MainSuite) TestUsage() {
+	code, _, stderr := exec("")
+	assert.Equal(s.T(), 2, code)
+	assert.Contains(s.T(), stderr, "wrap")
+	code, _, stderr = exec("", "unwrap")
+	assert.Equal(s.T(), 2, code)
+	assert.Contains(s.T(), stderr, "unknown command unwrap")
+	code, _, stderr = exec("", "wrap", "--help")
+	assert.Equal(s.T(), 2, code)
+	assert.Contains(s.T(), stderr, "-id-generator")
+	code, _, _ = exec("", "wrap", "--unknown")
+	assert.Equal(s.T(), 2, code)
+}
+
+func TestMainSuite(t *testing.T) {
+	suite.Run(t, new(MainSuite))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// signingKey returns the private key of fname, kid selects one if the file
+// holds several.
+func signingKey(fname string, kid string) (*c5.Key, error) {
+	keys, err := c5.LoadKeyFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var ret *c5.Key
+	for _, key := range keys {
+		if key.Private == nil || (kid != "" && key.ID != kid) {
+			continue
+		}
+		if ret != nil {
+			return nil, fmt.Errorf("%s holds several private keys, select one with --kid", fname)
+		}
+		ret = key
+	}
+	if ret == nil {
+		return nil, fmt.Errorf("%w: %s %s", c5.ErrNoSigningKey, fname, kid)
+	}
+	return ret, nil
+}
+
+func writeEnvelope(w io.Writer, env *c5.EnvelopeT) error {
+	dict, err := dictValue(env.ToDict())
+	if err != nil {
+		return err
+	}
+	return encodeJson("")(w, dict)
+}
+
+// writeFile replaces fname only after data is written completely.
+func writeFile(fname string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fname); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp := fname + ".tmp"
+	if err := os.WriteFile(tmp, data, mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp, fname)
+}
+
+// sign appends a signature to every envelope or writes detached
+// signatures, one JSON line per envelope. With -w the envelope files are
+// replaced and the detached signatures are written to <file>.sig.
+func (c *cli) sign(args []string) error {
+	fs := c.flags("sign", "[files]")
+	keyFile := fs.String("key", "", "file of the signing key, .jwk or .pem")
+	kid := fs.String("kid", "", "id of the key if the file holds several")
+	detached := fs.Bool("detached", false, "write the signatures instead of the envelopes")
+	counter := fs.Bool("counter", false, "countersign the signatures before")
+	inPlace := fs.Bool("w", false, "write into the files")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	fnames := fs.Args()
+	if *keyFile == "" || (*detached && *counter) || (*inPlace && len(fnames) == 0) {
+		fs.Usage()
+		return errUsage
+	}
+	key, err := signingKey(*keyFile, *kid)
+	if err != nil {
+		return err
+	}
+	signer := c5.NewSigner(key)
+	signTo := func(w io.Writer, fnames []string) error {
+		return c.envelopes(fnames, func(in *envelopeIn) error {
+			if in.err != nil {
+				return fmt.Errorf("%s: %w", in.name(), in.err)
+			}
+			if *detached {
+				sig, err := signer.DetachedSignature(c5.NewSimpleEnvelopeFromEnvelopeT(in.env, nil))
+				if err != nil {
+					return err
+				}
+				out, err := sig.Marshal()
+				if err != nil {
+					return err
+				}
+				_, err = w.Write(append(out, '\n'))
+				return err
+			}
+			sign := signer.Sign
+			if *counter {
+				sign = signer.Countersign
+			}
+			env, err := sign(in.env)
+			if err != nil {
+				return fmt.Errorf("%s: %w", in.name(), err)
+			}
+			return writeEnvelope(w, env)
+		})
+	}
+	if !*inPlace {
+		return signTo(c.stdout, fnames)
+	}
+	for _, fname := range fnames {
+		if fname == "-" {
+			fs.Usage()
+			return errUsage
+		}
+		buf := bytes.Buffer{}
+		if err := signTo(&buf, []string{fname}); err != nil {
+			return err
+		}
+		out := fname
+		if *detached {
+			out += ".sig"
+		}
+		if err := writeFile(out, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// keyDir creates the key of src in dir and its public part in dir/keys.
+func keyDir(t *testing.T, dir string, src string, alg string) (string, string) {
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "keys"), 0700))
+	private := filepath.Join(dir, alg+".jwk")
+	code, stdout, stderr := exec("", "keygen", "--src", src, "--alg", alg, "-o", private,
+		"--pub", filepath.Join(dir, "keys", alg+".pub.jwk"))
+	assert.Equal(t, 0, code, stderr)
+	return private, strings.TrimSpace(stdout)
+}
+
+type SignSuite struct {
+	suite.Suite
+	dir       string
+	key       string
+	kid       string
+	envelopes string
+}
+
+func (s *SignSuite) SetupTest() {
+	s.dir = s.T().TempDir()
+	s.key, s.kid = keyDir(s.T(), s.dir, "test case", "ed25519")
+	_, s.envelopes, _ = exec("{\"y\":4}\n{\"y\":5}\n", "wrap", "--src", "test case", "--kind", "k", "--t", "123")
+}
+
+func (s *SignSuite) TestStream() {
+	code, stdout, _ := exec(s.envelopes, "sign", "--key", s.key)
+	assert.Equal(s.T(), 0, code)
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	assert.Len(s.T(), lines, 2)
+	env, err := c5.UnmarshalEnvelopeTUseNumber([]byte(lines[0]))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "123-GUKeStj4aGQRju7p2Dzf31Qi2d2MVuRCw68H1c8gMCnQ", env.ID)
+	assert.Len(s.T(), env.Signatures, 1)
+	assert.Equal(s.T(), s.kid, env.Signatures[0].Kid)
+	assert.Equal(s.T(), "test case", env.Signatures[0].Src)
+	// the signature does not change the id
+	code, _, _ = exec(stdout, "verify")
+	assert.Equal(s.T(), 0, code)
+}
+
+func (s *SignSuite) TestInPlace() {
+	fname := filepath.Join(s.dir, "envelopes.ndjson")
+	assert.NoError(s.T(), os.WriteFile(fname, []byte(s.envelopes), 0640))
+	code, _, _ := exec("", "sign", "--key", s.key, "-w", fname)
+	assert.Equal(s.T(), 0, code)
+	other, _ := keyDir(s.T(), s.dir, "other", "es256")
+	code, _, _ = exec("", "sign", "--key", other, "--counter", "-w", fname)
+	assert.Equal(s.T(), 0, code)
+	data, err := os.ReadFile(fname)
+	assert.NoError(s.T(), err)
+	env, err := c5.UnmarshalEnvelopeTUseNumber([]byte(strings.Split(string(data), "\n")[1]))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), env.Signatures, 2)
+	assert.True(s.T(), env.Signatures[1].Counter)
+	info, err := os.Stat(fname)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), os.FileMode(0640), info.Mode().Perm())
+}
+
+func (s *SignSuite) TestDetached() {
+	fname := filepath.Join(s.dir, "envelopes.ndjson")
+	assert.NoError(s.T(), os.WriteFile(fname, []byte(s.envelopes), 0644))
+	code, _, _ := exec("", "sign", "--key", s.key, "--detached", "-w", fname)
+	assert.Equal(s.T(), 0, code)
+	data, err := os.ReadFile(fname)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), s.envelopes, string(data))
+	sigs, err := os.ReadFile(fname + ".sig")
+	assert.NoError(s.T(), err)
+	lines := strings.Split(strings.TrimSuffix(string(sigs), "\n"), "\n")
+	assert.Len(s.T(), lines, 2)
+	sig, err := c5.UnmarshalSignatureT([]byte(lines[1]))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), s.kid, sig.Kid)
+
+	code, stdout, _ := exec(s.envelopes, "sign", "--key", s.key, "--detached")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), 2, strings.Count(stdout, `"kid":"`+s.kid+`"`))
+}
+
+func (s *SignSuite) TestErrors() {
+	for _, args := range [][]string{{}, {"--key", s.key, "--detached", "--counter"}, {"--key", s.key, "-w"}, {"--key", s.key, "-w", "-"}} {
+		code, _, _ := exec(s.envelopes, append([]string{"sign"}, args...)...)
+		assert.Equal(s.T(), 2, code, args)
+	}
+	code, _, stderr := exec(s.envelopes, "sign", "--key", filepath.Join(s.dir, "keys", "ed25519.pub.jwk"))
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "no signing key")
+	code, _, stderr = exec(s.envelopes, "sign", "--key", s.key, "--kid", "other")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "no signing key")
+	code, _, stderr = exec(s.envelopes, "sign", "--key", s.key, "--counter")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "no signatures")
+	code, _, stderr = exec(`{"v":"A"}`, "sign", "--key", s.key)
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "stdin:0: missing field")
+}
+
+func TestSignSuite(t *testing.T) {
+	suite.Run(t, new(SignSuite))
+}
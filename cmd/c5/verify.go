@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"time"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+// sigList reads one detached signature per line.
+func sigList(fname string) ([][]byte, error) {
+	file, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	ret := [][]byte{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := scanner.Bytes(); len(line) > 0 {
+			ret = append(ret, append([]byte{}, line...))
+		}
+	}
+	return ret, scanner.Err()
+}
+
+func signatureLine(res *c5.SignatureResult) string {
+	sig := res.Signature
+	status := "ok"
+	if res.Err != nil {
+		status = res.Err.Error()
+	}
+	kind := "signature"
+	if sig.Counter {
+		kind = "countersignature"
+	}
+	return fmt.Sprintf("  %s %d by %q kid %s %s at %s: %s", kind, res.Index, sig.Src, sig.Kid, sig.Alg,
+		time.UnixMilli(int64(sig.T)).UTC().Format(c5.JSISOStringMillisFormat), status)
+}
+
+// verify checks the id of every envelope and fails if one does not match.
+// With --keys the signatures are checked against the keys of a directory
+// too and reported one per line.
+func (c *cli) verify(args []string) error {
+	fs := c.flags("verify", "[files]")
+	quiet := fs.Bool("q", false, "only report failures")
+	keys := fs.String("keys", "", "directory of .jwk and .pem keys to check the signatures")
+	threshold := fs.Int("threshold", 1, "valid signatures of distinct keys an envelope needs")
+	kids := stringList{}
+	fs.Var(&kids, "kid", "key ids which count for the threshold, default all")
+	sigs := fs.String("sig", "", "file of detached signatures, one line per envelope")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	if *keys == "" && (*sigs != "" || len(kids) > 0) {
+		fs.Usage()
+		return errUsage
+	}
+	var verifier *c5.Verifier
+	if *keys != "" {
+		if _, err := os.Stat(*keys); err != nil {
+			return err
+		}
+		store, err := c5.NewDirKeyStore(*keys)
+		if err != nil {
+			return err
+		}
+		verifier = c5.NewVerifier(c5.NewKeyring(store), &c5.SignaturePolicy{Threshold: *threshold, Kids: kids})
+	}
+	var detached [][]byte
+	if *sigs != "" {
+		var err error
+		if detached, err = sigList(*sigs); err != nil {
+			return err
+		}
+	}
+	count := 0
+	failed := 0
+	err := c.envelopes(fs.Args(), func(in *envelopeIn) error {
+		count++
+		err := in.err
+		if err == nil {
+			err = c5.VerifyID(in.env)
+		}
+		results := []c5.SignatureResult{}
+		if err == nil && verifier != nil && detached != nil {
+			if count > len(detached) {
+				err = fmt.Errorf("%w: no detached signature", c5.ErrNoSignatures)
+			} else {
+				res, verr := verifier.VerifyDetached(in.raw, detached[count-1])
+				if res != nil {
+					results = append(results, *res)
+				}
+				err = verr
+			}
+		} else if err == nil && verifier != nil {
+			results, err = verifier.Verify(in.env)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(c.stdout, "FAIL %s: %v\n", in.name(), err)
+		} else if !*quiet {
+			fmt.Fprintf(c.stdout, "ok %s\n", in.name())
+		}
+		if err != nil || !*quiet {
+			for idx := range results {
+				fmt.Fprintln(c.stdout, signatureLine(&results[idx]))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if detached != nil && len(detached) != count {
+		fmt.Fprintf(c.stdout, "FAIL %s: %d signatures for %d envelopes\n", *sigs, len(detached), count)
+		return errFailed
+	}
+	if failed > 0 {
+		return errFailed
+	}
+	return nil
+}
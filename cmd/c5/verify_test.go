@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type VerifySuite struct {
+	suite.Suite
+	envelopes string
+}
+
+func (s *VerifySuite) SetupTest() {
+	_, s.envelopes, _ = exec("{\"y\":4}\n{\"y\":5}\n", "wrap", "--src", "s", "--kind", "k", "--t", "123")
+}
+
+func (s *VerifySuite) TestValid() {
+	code, stdout, _ := exec(s.envelopes, "verify")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), "ok 123-GUKeStj4aGQRju7p2Dzf31Qi2d2MVuRCw68H1c8gMCnQ\n", strings.SplitAfter(stdout, "\n")[0])
+	code, stdout, _ = exec(s.envelopes, "verify", "-q")
+	assert.Equal(s.T(), 0, code)
+	assert.Empty(s.T(), stdout)
+}
+
+func (s *VerifySuite) TestTampered() {
+	tampered := strings.Replace(s.envelopes, `"y":5`, `"y":6`, 1)
+	code, stdout, _ := exec(tampered, "verify", "-q")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "FAIL 123-")
+	assert.Equal(s.T(), 1, strings.Count(stdout, "\n"))
+
+	code, stdout, _ = exec(`{"v":"A"}`, "verify")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "FAIL stdin:0: missing field")
+
+	code, _, stderr := exec(`{"v":`, "verify")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "unexpected EOF")
+}
+
+func (s *VerifySuite) TestFiles() {
+	fname := filepath.Join(s.T().TempDir(), "envelopes.ndjson")
+	assert.NoError(s.T(), os.WriteFile(fname, []byte(s.envelopes), 0644))
+	code, stdout, _ := exec("", "verify", fname, "-")
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), 2, strings.Count(stdout, "ok "))
+	code, _, stderr := exec("", "verify", fname+".missing")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "no such file")
+}
+
+func (s *VerifySuite) TestKeys() {
+	dir := s.T().TempDir()
+	keys := filepath.Join(dir, "keys")
+	key, kid := keyDir(s.T(), dir, "s", "ed25519")
+	other, otherKid := keyDir(s.T(), dir, "other", "es384")
+	_, signed, _ := exec(s.envelopes, "sign", "--key", key)
+	_, countersigned, _ := exec(signed, "sign", "--key", other, "--counter")
+
+	code, stdout, _ := exec(countersigned, "verify", "--keys", keys)
+	assert.Equal(s.T(), 0, code)
+	lines := strings.Split(stdout, "\n")
+	assert.Equal(s.T(), "ok 123-GUKeStj4aGQRju7p2Dzf31Qi2d2MVuRCw68H1c8gMCnQ", lines[0])
+	assert.Regexp(s.T(), `^  signature 0 by "s" kid `+kid+` EdDSA at \d{4}-\d\d-\d\dT[\d:.]+Z: ok$`, lines[1])
+	assert.Regexp(s.T(), `^  countersignature 1 by "other" kid `+otherKid+` ES384 at .*: ok$`, lines[2])
+
+	code, stdout, _ = exec(signed, "verify", "--keys", keys, "--threshold", "2", "-q")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "FAIL 123-GUKe")
+	assert.Contains(s.T(), stdout, "signature policy not satisfied: 1 of 2 valid signatures")
+	code, _, _ = exec(countersigned, "verify", "--keys", keys, "--threshold", "2", "--kid", kid+","+otherKid)
+	assert.Equal(s.T(), 0, code)
+	code, _, _ = exec(countersigned, "verify", "--keys", keys, "--kid", "unknown")
+	assert.Equal(s.T(), 1, code)
+
+	code, stdout, _ = exec(s.envelopes, "verify", "--keys", keys, "-q")
+	assert.Equal(s.T(), 1, code)
+	assert.Equal(s.T(), 2, strings.Count(stdout, ": no signatures\n"))
+
+	// the key of the signer is unknown
+	assert.NoError(s.T(), os.Remove(filepath.Join(keys, "ed25519.pub.jwk")))
+	code, stdout, _ = exec(signed, "verify", "--keys", keys)
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "key not found")
+
+	tampered := strings.Replace(countersigned, `"sig":"`, `"sig":"AA`, 1)
+	code, stdout, _ = exec(tampered, "verify", "--keys", keys)
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "signature invalid")
+}
+
+func (s *VerifySuite) TestDetached() {
+	dir := s.T().TempDir()
+	key, _ := keyDir(s.T(), dir, "s", "ed25519")
+	fname := filepath.Join(dir, "envelopes.ndjson")
+	assert.NoError(s.T(), os.WriteFile(fname, []byte(s.envelopes), 0644))
+	code, _, _ := exec("", "sign", "--key", key, "--detached", "-w", fname)
+	assert.Equal(s.T(), 0, code)
+	code, stdout, _ := exec("", "verify", "--keys", filepath.Join(dir, "keys"), "--sig", fname+".sig", fname)
+	assert.Equal(s.T(), 0, code)
+	assert.Equal(s.T(), 2, strings.Count(stdout, ": ok\n"))
+
+	code, stdout, _ = exec(strings.SplitAfter(s.envelopes, "\n")[0], "verify", "--keys", filepath.Join(dir, "keys"), "--sig", fname+".sig")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "2 signatures for 1 envelopes")
+	code, stdout, _ = exec(strings.Replace(s.envelopes, `"y":4`, `"y":6`, 1)+s.envelopes, "verify", "-q", "--keys", filepath.Join(dir, "keys"), "--sig", fname+".sig")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "id does not match")
+	assert.Contains(s.T(), stdout, "no detached signature")
+
+	code, _, _ = exec(s.envelopes, "verify", "--sig", fname+".sig")
+	assert.Equal(s.T(), 2, code)
+	code, _, _ = exec(s.envelopes, "verify", "--keys", filepath.Join(dir, "missing"))
+	assert.Equal(s.T(), 1, code)
+}
+
+func (s *VerifySuite) TestDetachedLargeInteger() {
+	dir := s.T().TempDir()
+	key, _ := keyDir(s.T(), dir, "s", "ed25519")
+	code, wrapped, _ := exec(`{"b":9007199254740993}`, "wrap", "--src", "s", "--kind", "big")
+	assert.Equal(s.T(), 0, code)
+	assert.Contains(s.T(), wrapped, `"b":9007199254740993`)
+	fname := filepath.Join(dir, "big.ndjson")
+	assert.NoError(s.T(), os.WriteFile(fname, []byte(wrapped), 0644))
+	code, _, _ = exec("", "sign", "--key", key, "--detached", "-w", fname)
+	assert.Equal(s.T(), 0, code)
+	code, stdout, _ := exec("", "verify", "--keys", filepath.Join(dir, "keys"), "--sig", fname+".sig", fname)
+	assert.Equal(s.T(), 0, code, stdout)
+	assert.Equal(s.T(), 1, strings.Count(stdout, ": ok\n"))
+}
+
+func (s *VerifySuite) TestZeroTTL() {
+	dir := s.T().TempDir()
+	key, _ := keyDir(s.T(), dir, "s", "ed25519")
+	received := strings.Replace(strings.SplitAfter(s.envelopes, "\n")[0], `"ttl":10`, `"ttl":0`, 1)
+	code, signed, _ := exec(received, "sign", "--key", key)
+	assert.Equal(s.T(), 0, code)
+	assert.Contains(s.T(), signed, `"ttl":0`)
+	code, stdout, _ := exec(signed, "verify", "--keys", filepath.Join(dir, "keys"))
+	assert.Equal(s.T(), 0, code, stdout)
+
+	code, stdout, _ = exec(strings.Replace(signed, `"ttl":0`, `"ttl":10`, 1), "verify", "--keys", filepath.Join(dir, "keys"))
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stdout, "signature invalid")
+}
+
+func TestVerifySuite(t *testing.T) {
+	suite.Run(t, new(VerifySuite))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	ogs "github.com/mabels/object-graph-streamer"
+
+	c5 "github.com/mabels/c5-envelope/pkg"
+)
+
+var idGenerators = map[string]c5.IdGeneratorFn{
+	"thash": c5.THashIdGenerator,
+	"hash":  c5.HashIdGenerator,
+}
+
+// wrap creates an envelope per JSON object on stdin.
+func (c *cli) wrap(args []string) error {
+	fs := c.flags("wrap", "< data.json")
+	src := fs.String("src", "", "source of the envelopes")
+	dst := stringList{}
+	fs.Var(&dst, "dst", "destination, repeatable or comma separated")
+	kind := fs.String("kind", "", "kind of the payload")
+	ttl := fs.Int("ttl", 10, "hop limit")
+	indent := fs.Int("indent", 0, "indent of the JSON output")
+	idGenerator := fs.String("id-generator", "thash", "thash for t-hash ids or hash")
+	t := fs.Int64("t", 0, "time in milliseconds since 1970, default now")
+	if err := parse(fs, args); err != nil {
+		return err
+	}
+	generator, found := idGenerators[*idGenerator]
+	if *src == "" || *kind == "" || !found || fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+	var jsonProp *ogs.JsonProps
+	if *indent > 0 {
+		jsonProp = ogs.NewJsonProps(*indent, "")
+	}
+	return jsonValues(c.stdin, func(raw json.RawMessage) error {
+		data := map[string]interface{}{}
+		if err := decodeJson(raw, &data); err != nil {
+			return fmt.Errorf("data must be a JSON object: %w", err)
+		}
+		props := c5.SimpleEnvelopeProps{
+			Src:         *src,
+			Dst:         dst,
+			TTL:         *ttl,
+			Data:        c5.PayloadT1{Kind: *kind, Data: data},
+			JsonProp:    jsonProp,
+			IdGenerator: generator,
+		}
+		if *t != 0 {
+			props.T = *t
+		}
+		_, err := io.WriteString(c.stdout, *c5.NewSimpleEnvelope(&props).AsJson()+"\n")
+		return err
+	})
+}
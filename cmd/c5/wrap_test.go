@@ -0,0 +1,52 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WrapSuite struct {
+	suite.Suite
+}
+
+func (s *WrapSuite) TestWrap() {
+	code, stdout, _ := exec(`{"name":"object","date":"2021-05-20"}`,
+		"wrap", "--src", "test case", "--kind", "test", "--t", "1624140000000")
+	assert.Equal(s.T(), 0, code)
+	// the envelope of src/simpleEnvelope.test.ts
+	assert.Equal(s.T(), `{"data":{"data":{"date":"2021-05-20","name":"object"},"kind":"test"},"dst":[],"id":"1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp","src":"test case","t":1624140000000,"ttl":10,"v":"A"}`+"\n", stdout)
+}
+
+func (s *WrapSuite) TestFlags() {
+	code, stdout, _ := exec("{\"y\":4}\n{\"y\":5}\n", "wrap", "--src", "s", "--kind", "k",
+		"--dst", "a,b", "--dst", "c", "--ttl", "3", "--indent", "2", "--id-generator", "hash", "--t", "1")
+	assert.Equal(s.T(), 0, code)
+	lines := strings.Split(stdout, "\n}\n")
+	assert.Len(s.T(), lines, 3)
+	assert.Contains(s.T(), lines[0], "\"dst\": [\n    \"a\",\n    \"b\",\n    \"c\"\n  ]")
+	assert.Contains(s.T(), lines[0], `"id": "GUKeStj4aGQRju7p2Dzf31Qi2d2MVuRCw68H1c8gMCnQ"`)
+	assert.Contains(s.T(), lines[0], `"ttl": 3`)
+}
+
+func (s *WrapSuite) TestBigNumbers() {
+	code, stdout, _ := exec(`{"big":12345678901234567890,"f":1.50}`, "wrap", "--src", "s", "--kind", "k", "--t", "1")
+	assert.Equal(s.T(), 0, code)
+	assert.Contains(s.T(), stdout, `{"big":12345678901234567890,"f":1.5}`)
+}
+
+func (s *WrapSuite) TestErrors() {
+	code, _, _ := exec(`{}`, "wrap", "--kind", "k")
+	assert.Equal(s.T(), 2, code)
+	code, _, _ = exec(`{}`, "wrap", "--src", "s", "--kind", "k", "--id-generator", "uuid")
+	assert.Equal(s.T(), 2, code)
+	code, _, stderr := exec(`[1]`, "wrap", "--src", "s", "--kind", "k")
+	assert.Equal(s.T(), 1, code)
+	assert.Contains(s.T(), stderr, "data must be a JSON object")
+}
+
+func TestWrapSuite(t *testing.T) {
+	suite.Run(t, new(WrapSuite))
+}
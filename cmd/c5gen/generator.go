@@ -0,0 +1,670 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// schema is the subset of JSON Schema c5gen understands.
+type schema struct {
+	Ref         string             `json:"$ref"`
+	Type        string             `json:"type"`
+	Description string             `json:"description"`
+	Properties  map[string]*schema `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *schema            `json:"items"`
+	Enum        []string           `json:"enum"`
+	Definitions map[string]*schema `json:"definitions"`
+	Defs        map[string]*schema `json:"$defs"`
+	// Kind registers the definition as the data of this payload kind
+	Kind string `json:"x-kind"`
+}
+
+type typeKind int
+
+const (
+	tAny typeKind = iota
+	tString
+	tNumber
+	tInteger
+	tBool
+	tMap
+	tArray
+	tRef
+)
+
+type goType struct {
+	kind typeKind
+	elem *goType
+	ref  string
+}
+
+type field struct {
+	name        string
+	goName      string
+	description string
+	typ         *goType
+	required    bool
+}
+
+type definition struct {
+	name        string
+	description string
+	kind        string
+	fields      []field
+	enum        []string
+	isMap       bool
+}
+
+func (d *definition) isStruct() bool {
+	return !d.isMap && d.enum == nil
+}
+
+type generator struct {
+	pkg    string
+	q      string
+	source string
+	defs   map[string]*definition
+	buf    bytes.Buffer
+}
+
+// Generate returns the Go source of the definitions of the JSON Schema
+// src. Code for the package c5 itself refers to the envelope package
+// without qualifier.
+func Generate(pkg string, source string, src []byte) ([]byte, error) {
+	root := schema{}
+	if err := json.Unmarshal(src, &root); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	g := &generator{pkg: pkg, source: source, defs: map[string]*definition{}}
+	if pkg != "c5" {
+		g.q = "c5."
+	}
+	schemas := root.Definitions
+	if schemas == nil {
+		schemas = root.Defs
+	}
+	for _, name := range sortedNames(schemas) {
+		if err := g.define(name, schemas[name]); err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	for _, def := range g.defs {
+		for _, fl := range def.fields {
+			if err := g.checkRefs(fl.typ); err != nil {
+				return nil, fmt.Errorf("%s: %s.%s: %w", source, def.name, fl.name, err)
+			}
+		}
+	}
+	g.emit()
+	out, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w\n%s", source, err, g.buf.String())
+	}
+	return out, nil
+}
+
+func sortedNames(m map[string]*schema) []string {
+	ret := make([]string, 0, len(m))
+	for name := range m {
+		ret = append(ret, name)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func (g *generator) define(name string, s *schema) error {
+	def := &definition{name: name, description: s.Description, kind: s.Kind}
+	if _, found := g.defs[name]; found {
+		return fmt.Errorf("%s defined twice", name)
+	}
+	g.defs[name] = def
+	switch {
+	case len(s.Enum) > 0:
+		if s.Type != "string" {
+			return fmt.Errorf("%s: only string enums are supported", name)
+		}
+		def.enum = s.Enum
+	case s.Type == "object" && len(s.Properties) == 0:
+		def.isMap = true
+	case s.Type == "object":
+		required := map[string]bool{}
+		for _, req := range s.Required {
+			required[req] = true
+		}
+		for _, prop := range sortedNames(s.Properties) {
+			fl := field{
+				name:        prop,
+				goName:      goName(prop),
+				description: s.Properties[prop].Description,
+				required:    required[prop],
+			}
+			typ, err := g.resolve(name+fl.goName, s.Properties[prop])
+			if err != nil {
+				return fmt.Errorf("%s.%s: %w", name, prop, err)
+			}
+			fl.typ = typ
+			def.fields = append(def.fields, fl)
+		}
+	default:
+		return fmt.Errorf("%s: definitions must be objects or string enums", name)
+	}
+	if def.kind != "" && !def.isStruct() {
+		return fmt.Errorf("%s: x-kind needs an object with properties", name)
+	}
+	return nil
+}
+
+// resolve returns the type of s, inline objects and enums become
+// definitions named by their path.
+func (g *generator) resolve(path string, s *schema) (*goType, error) {
+	if s.Ref != "" {
+		for _, prefix := range []string{"#/definitions/", "#/$defs/"} {
+			if strings.HasPrefix(s.Ref, prefix) {
+				return &goType{kind: tRef, ref: strings.TrimPrefix(s.Ref, prefix)}, nil
+			}
+		}
+		return nil, fmt.Errorf("unsupported $ref %s", s.Ref)
+	}
+	if len(s.Enum) > 0 || (s.Type == "object" && len(s.Properties) > 0) {
+		if err := g.define(path, s); err != nil {
+			return nil, err
+		}
+		return &goType{kind: tRef, ref: path}, nil
+	}
+	switch s.Type {
+	case "":
+		return &goType{kind: tAny}, nil
+	case "string":
+		return &goType{kind: tString}, nil
+	case "number":
+		return &goType{kind: tNumber}, nil
+	case "integer":
+		return &goType{kind: tInteger}, nil
+	case "boolean":
+		return &goType{kind: tBool}, nil
+	case "object":
+		return &goType{kind: tMap}, nil
+	case "array":
+		if s.Items == nil {
+			return &goType{kind: tArray, elem: &goType{kind: tAny}}, nil
+		}
+		elem, err := g.resolve(path+"Item", s.Items)
+		if err != nil {
+			return nil, err
+		}
+		return &goType{kind: tArray, elem: elem}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", s.Type)
+}
+
+func (g *generator) checkRefs(t *goType) error {
+	switch t.kind {
+	case tRef:
+		if _, found := g.defs[t.ref]; !found {
+			return fmt.Errorf("undefined $ref %s", t.ref)
+		}
+	case tArray:
+		return g.checkRefs(t.elem)
+	}
+	return nil
+}
+
+var initialisms = map[string]string{
+	"id":   "ID",
+	"ttl":  "TTL",
+	"url":  "URL",
+	"uri":  "URI",
+	"uuid": "UUID",
+	"json": "JSON",
+	"http": "HTTP",
+	"ip":   "IP",
+}
+
+// goName converts a property name, words are split at non alphanumerics
+// and after digits: "x5t#S256" is X5TS256, "ttl" is TTL.
+func goName(name string) string {
+	var words []string
+	word := []rune{}
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = []rune{}
+		}
+	}
+	for _, c := range name {
+		switch {
+		case !unicode.IsLetter(c) && !unicode.IsDigit(c):
+			flush()
+		case unicode.IsLetter(c) && len(word) > 0 && unicode.IsDigit(word[len(word)-1]):
+			flush()
+			word = append(word, c)
+		default:
+			word = append(word, c)
+		}
+	}
+	flush()
+	var b strings.Builder
+	for _, w := range words {
+		if initialism, found := initialisms[strings.ToLower(w)]; found {
+			b.WriteString(initialism)
+			continue
+		}
+		runes := []rune(w)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	if b.Len() == 0 || unicode.IsDigit([]rune(b.String())[0]) {
+		return "F" + b.String()
+	}
+	return b.String()
+}
+
+func (g *generator) p(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+	g.buf.WriteByte('\n')
+}
+
+func (g *generator) typeExpr(t *goType) string {
+	switch t.kind {
+	case tString:
+		return "string"
+	case tNumber:
+		return "float64"
+	case tInteger:
+		return "int64"
+	case tBool:
+		return "bool"
+	case tMap:
+		return "map[string]interface{}"
+	case tArray:
+		return "[]" + g.typeExpr(t.elem)
+	case tRef:
+		return t.ref
+	}
+	return "interface{}"
+}
+
+// pointer reports whether the field is a pointer, optional structs are.
+func (g *generator) pointer(fl field) bool {
+	return !fl.required && fl.typ.kind == tRef && g.defs[fl.typ.ref].isStruct()
+}
+
+func (g *generator) emit() {
+	g.p("// Code generated by c5gen from %s. DO NOT EDIT.", g.source)
+	g.p("")
+	g.p("package %s", g.pkg)
+	g.p("")
+	g.p("import (")
+	names := []string{}
+	for name := range g.defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hasStruct, hasEnum, hasKind := false, false, false
+	for _, name := range names {
+		def := g.defs[name]
+		hasStruct = hasStruct || def.isStruct()
+		hasEnum = hasEnum || def.enum != nil
+		hasKind = hasKind || def.kind != ""
+	}
+	if hasStruct {
+		g.p(`"encoding/json"`)
+	}
+	if hasStruct || hasEnum {
+		g.p(`"fmt"`)
+	}
+	if hasKind {
+		g.p(`"reflect"`)
+	}
+	if g.q != "" && (hasStruct || hasEnum) {
+		g.p("")
+		g.p(`c5 "github.com/mabels/c5-envelope/pkg"`)
+	}
+	g.p(")")
+	for _, name := range names {
+		def := g.defs[name]
+		g.p("")
+		if def.description != "" {
+			g.p("// %s %s", name, def.description)
+		}
+		switch {
+		case def.isMap:
+			g.p("type %s map[string]interface{}", name)
+		case def.enum != nil:
+			g.emitEnum(def)
+		default:
+			g.emitStruct(def)
+		}
+	}
+	if hasKind {
+		g.p("")
+		g.p("func init() {")
+		for _, name := range names {
+			def := g.defs[name]
+			if def.kind == "" {
+				continue
+			}
+			g.p("%sRegisterKind(%sKind{", g.q, g.q)
+			g.p("Name: %q,", def.kind)
+			g.p("Type: reflect.TypeOf(%s{}),", name)
+			g.p("FromDict: func(data map[string]interface{}) (%sKindData, error) {", g.q)
+			g.p("r := &%s{}", name)
+			g.p("return r, FromDict%s(data, r)", name)
+			g.p("},")
+			g.p("})")
+		}
+		g.p("}")
+	}
+}
+
+func enumConst(def *definition, value string) string {
+	var b strings.Builder
+	for _, c := range value {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			b.WriteRune(c)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return def.name + "_" + b.String()
+}
+
+func (g *generator) emitEnum(def *definition) {
+	g.p("type %s string", def.name)
+	g.p("")
+	g.p("const (")
+	for _, value := range def.enum {
+		g.p("%s %s = %q", enumConst(def, value), def.name, value)
+	}
+	g.p(")")
+	g.p("")
+	g.p("func From%s(v string) (%s, error) {", def.name, def.name)
+	g.p("switch v {")
+	for _, value := range def.enum {
+		g.p("case %q:", value)
+		g.p("return %s, nil", enumConst(def, value))
+	}
+	g.p("}")
+	g.p(`return "", fmt.Errorf("%%w: %s %%s", %sErrDictEnum, v)`, def.name, g.q)
+	g.p("}")
+	g.p("")
+	g.p("func To%s(v %s) string {", def.name, def.name)
+	g.p("switch v {")
+	for _, value := range def.enum {
+		g.p("case %s:", enumConst(def, value))
+		g.p("return %q", value)
+	}
+	g.p("}")
+	g.p(`panic(fmt.Sprintf("%s with unknown value:%%s", string(v)))`, def.name)
+	g.p("}")
+	g.p("")
+	g.p("// EnumValues are the values of %s in the JSON Schema.", def.name)
+	g.p("func (%s) EnumValues() []string {", def.name)
+	values := make([]string, len(def.enum))
+	for idx, value := range def.enum {
+		values[idx] = strconv.Quote(value)
+	}
+	g.p("return []string{%s}", strings.Join(values, ", "))
+	g.p("}")
+}
+
+func (g *generator) emitStruct(def *definition) {
+	name := def.name
+	g.p("type %s struct {", name)
+	for _, fl := range def.fields {
+		typ := g.typeExpr(fl.typ)
+		if g.pointer(fl) {
+			typ = "*" + typ
+		}
+		tag := fl.name
+		if !fl.required {
+			tag += ",omitempty"
+		}
+		if fl.description == "" {
+			g.p("%s %s `json:%q`", fl.goName, typ, tag)
+			continue
+		}
+		for _, line := range strings.Split(fl.description, "\n") {
+			g.p("// %s", strings.TrimSpace(line))
+		}
+		// the tag is read by JSONSchema
+		description := strings.ReplaceAll(fl.description, "`", "'")
+		g.p("%s %s `json:%q description:%q`", fl.goName, typ, tag, description)
+	}
+	g.p("}")
+	g.p("")
+	g.p("func (r *%s) Marshal() ([]byte, error) {", name)
+	g.p("return json.Marshal(r)")
+	g.p("}")
+	g.p("")
+	g.p("func Unmarshal%s(data []byte) (*%s, error) {", name, name)
+	g.p("dict := map[string]interface{}{}")
+	g.p("err := json.Unmarshal(data, &dict)")
+	g.p("if err != nil {")
+	g.p("return nil, err")
+	g.p("}")
+	g.p("ins := %s{}", name)
+	g.p("return &ins, FromDict%s(dict, &ins)", name)
+	g.p("}")
+	if def.kind != "" {
+		g.p("")
+		g.p("// Payload is the payload of kind %q with r as data.", def.kind)
+		g.p("func (r *%s) Payload() %sPayloadT1 {", name, g.q)
+		g.p("return %sPayloadT1{Kind: %q, Data: r.ToDict()}", g.q, def.kind)
+		g.p("}")
+	}
+	g.p("")
+	g.p("func (r *%s) ToDict() map[string]interface{} {", name)
+	g.p("dict := map[string]interface{}{}")
+	for _, fl := range def.fields {
+		src := "r." + fl.goName
+		dst := fmt.Sprintf("dict[%q]", fl.name)
+		if fl.required {
+			g.toDict(dst, src, fl.typ, 0, false)
+			continue
+		}
+		g.p("if %s {", g.present(src, fl))
+		g.toDict(dst, src, fl.typ, 0, true)
+		g.p("}")
+	}
+	g.p("return dict")
+	g.p("}")
+	g.p("")
+	g.p("func FromDict%s(data map[string]interface{}, r *%s) error {", name, name)
+	for _, fl := range def.fields {
+		ctx := name + "." + fl.name
+		g.p("if v, ok := data[%q]; ok && v != nil {", fl.name)
+		target := "r." + fl.goName
+		if g.pointer(fl) {
+			g.p("%s = &%s{}", target, fl.typ.ref)
+			target = "*" + target
+		}
+		g.fromDict(target, "v", fl.typ, 0, ctx, nil)
+		if fl.required {
+			g.p("} else {")
+			g.p(`return fmt.Errorf("%%w: %s", %sErrDictMissing)`, ctx, g.q)
+		}
+		g.p("}")
+	}
+	g.p("return nil")
+	g.p("}")
+}
+
+// present is the condition under which an optional field is in the dict.
+func (g *generator) present(src string, fl field) string {
+	switch fl.typ.kind {
+	case tString:
+		return src + ` != ""`
+	case tNumber, tInteger:
+		return src + " != 0"
+	case tBool:
+		return src
+	case tMap, tArray:
+		return "len(" + src + ") > 0"
+	case tRef:
+		def := g.defs[fl.typ.ref]
+		switch {
+		case def.isMap:
+			return "len(" + src + ") > 0"
+		case def.enum != nil:
+			return src + ` != ""`
+		}
+	}
+	return src + " != nil"
+}
+
+func suffix(depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	return fmt.Sprint(depth)
+}
+
+// toDict assigns the dict value of src to dst, temporaries are declared
+// in a block unless the code is scoped already.
+func (g *generator) toDict(dst string, src string, t *goType, depth int, scoped bool) {
+	openBlock := func() {
+		if !scoped {
+			g.p("{")
+		}
+	}
+	closeBlock := func() {
+		if !scoped {
+			g.p("}")
+		}
+	}
+	switch t.kind {
+	case tMap:
+		openBlock()
+		g.copyMap(dst, src, depth)
+		closeBlock()
+	case tArray:
+		tmp := "tmp" + suffix(depth)
+		openBlock()
+		switch t.elem.kind {
+		case tString, tNumber, tInteger, tBool, tAny:
+			g.p("%s := make(%s, len(%s))", tmp, g.typeExpr(t), src)
+			g.p("copy(%s, %s)", tmp, src)
+		default:
+			idx, item := "idx"+suffix(depth), "item"+suffix(depth)
+			g.p("%s := make([]interface{}, len(%s))", tmp, src)
+			g.p("for %s, %s := range %s {", idx, item, src)
+			g.toDict(fmt.Sprintf("%s[%s]", tmp, idx), item, t.elem, depth+1, true)
+			g.p("}")
+		}
+		g.p("%s = %s", dst, tmp)
+		closeBlock()
+	case tRef:
+		def := g.defs[t.ref]
+		switch {
+		case def.isMap:
+			openBlock()
+			g.copyMap(dst, src, depth)
+			closeBlock()
+		case def.enum != nil:
+			g.p("%s = To%s(%s)", dst, def.name, src)
+		default:
+			g.p("%s = %s.ToDict()", dst, src)
+		}
+	default:
+		g.p("%s = %s", dst, src)
+	}
+}
+
+func (g *generator) copyMap(dst string, src string, depth int) {
+	tmp := "tmp" + suffix(depth)
+	g.p("%s := make(map[string]interface{}, len(%s))", tmp, src)
+	g.p("for key, val := range %s {", src)
+	g.p("%s[key] = val", tmp)
+	g.p("}")
+	g.p("%s = %s", dst, tmp)
+}
+
+// fromDict converts src into target, errors are returned with the path
+// ctx of the value, args are the array indices in ctx.
+func (g *generator) fromDict(target string, src string, t *goType, depth int, ctx string, args []string) {
+	errorf := func() string {
+		return fmt.Sprintf(`return fmt.Errorf("%s: %%w", %s)`, ctx, strings.Join(append(append([]string{}, args...), "err"), ", "))
+	}
+	convert := func(fn string) {
+		tmp := "tmp" + suffix(depth)
+		g.p("%s, err := %s%s(%s)", tmp, g.q, fn, src)
+		g.p("if err != nil {")
+		g.p("%s", errorf())
+		g.p("}")
+		g.p("%s = %s", target, tmp)
+	}
+	switch t.kind {
+	case tString:
+		convert("AsString")
+	case tNumber:
+		convert("AsFloat64")
+	case tInteger:
+		convert("AsInt64")
+	case tBool:
+		convert("AsBool")
+	case tMap:
+		g.fromObject(target, src, "map[string]interface{}", depth, errorf)
+	case tArray:
+		arr, idx, item := "arr"+suffix(depth), "idx"+suffix(depth), "item"+suffix(depth)
+		g.p("%s, err := %sAsArray(%s)", arr, g.q, src)
+		g.p("if err != nil {")
+		g.p("%s", errorf())
+		g.p("}")
+		g.p("%s = make(%s, len(%s))", target, g.typeExpr(t), arr)
+		g.p("for %s, %s := range %s {", idx, item, arr)
+		g.fromDict(fmt.Sprintf("%s[%s]", target, idx), item, t.elem, depth+1,
+			ctx+"[%d]", append(append([]string{}, args...), idx))
+		g.p("}")
+	case tRef:
+		def := g.defs[t.ref]
+		switch {
+		case def.isMap:
+			g.fromObject(target, src, def.name, depth, errorf)
+		case def.enum != nil:
+			str := "str" + suffix(depth)
+			g.p("%s, err := %sAsString(%s)", str, g.q, src)
+			g.p("if err != nil {")
+			g.p("%s", errorf())
+			g.p("}")
+			g.p("%s, err = From%s(%s)", target, def.name, str)
+			g.p("if err != nil {")
+			g.p("%s", errorf())
+			g.p("}")
+		default:
+			obj := "obj" + suffix(depth)
+			g.p("%s, err := %sAsObject(%s)", obj, g.q, src)
+			g.p("if err != nil {")
+			g.p("%s", errorf())
+			g.p("}")
+			ref := "&" + target
+			if strings.HasPrefix(target, "*") {
+				ref = target[1:]
+			}
+			g.p("if err := FromDict%s(%s, %s); err != nil {", def.name, obj, ref)
+			g.p("%s", errorf())
+			g.p("}")
+		}
+	default:
+		g.p("%s = %s", target, src)
+	}
+}
+
+func (g *generator) fromObject(target string, src string, typ string, depth int, errorf func() string) {
+	obj := "obj" + suffix(depth)
+	g.p("%s, err := %sAsObject(%s)", obj, g.q, src)
+	g.p("if err != nil {")
+	g.p("%s", errorf())
+	g.p("}")
+	g.p("%s = make(%s, len(%s))", target, typ, obj)
+	g.p("for key, val := range %s {", obj)
+	g.p("%s[key] = val", target)
+	g.p("}")
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type GeneratorSuite struct {
+	suite.Suite
+}
+
+func (s *GeneratorSuite) generate(pkg string, fname string) string {
+	src, err := os.ReadFile(fname)
+	assert.NoError(s.T(), err)
+	code, err := Generate(pkg, filepath.Base(fname), src)
+	assert.NoError(s.T(), err)
+	return string(code)
+}
+
+// TestCheckedIn fails if pkg was not regenerated after a schema change.
+func (s *GeneratorSuite) TestCheckedIn() {
+	for schema, out := range map[string]string{
+		"../../schema/envelope.schema.json": "../../pkg/envelope.go",
+		"../../schema/kinds.schema.json":    "../../pkg/payload_kinds.go",
+	} {
+		code, err := os.ReadFile(out)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), string(code), s.generate("c5", schema), out)
+	}
+}
+
+func (s *GeneratorSuite) TestFieldDescription() {
+	code := s.generate("c5", "../../schema/envelope.schema.json")
+	assert.Contains(s.T(), code, "\t// UTC milliseconds since 1970\n\tT float64 `json:\"t\" description:\"UTC milliseconds since 1970\"`\n")
+}
+
+func (s *GeneratorSuite) TestGoName() {
+	for name, expected := range map[string]string{
+		"id":        "ID",
+		"ttl":       "TTL",
+		"x5c":       "X5C",
+		"x5t#S256":  "X5TS256",
+		"user_id":   "UserID",
+		"camelCase": "CamelCase",
+		"3d":        "F3D",
+	} {
+		assert.Equal(s.T(), expected, goName(name))
+	}
+}
+
+func (s *GeneratorSuite) TestOtherPackage() {
+	code := s.generate("order", "testdata/order.schema.json")
+	assert.Contains(s.T(), code, `c5 "github.com/mabels/c5-envelope/pkg"`)
+	assert.Regexp(s.T(), "Customer +\\*OrderCustomer +`json:\"customer,omitempty\"`", code)
+	assert.Contains(s.T(), code, "State_in_progress State = \"in-progress\"")
+	assert.Contains(s.T(), code, `Name: "test.order"`)
+	if testing.Short() {
+		return
+	}
+	// compile and run the generated code against the envelope package
+	dir, err := os.MkdirTemp("testdata", "order")
+	assert.NoError(s.T(), err)
+	defer os.RemoveAll(dir)
+	assert.NoError(s.T(), os.WriteFile(filepath.Join(dir, "order.go"), []byte(code), 0644))
+	test, err := os.ReadFile("testdata/order_test.go.txt")
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), os.WriteFile(filepath.Join(dir, "order_test.go"), test, 0644))
+	out, err := exec.Command("go", "test", "./"+dir).CombinedOutput()
+	assert.NoError(s.T(), err, string(out))
+}
+
+func (s *GeneratorSuite) TestSchemaErrors() {
+	for _, src := range []string{
+		`{"definitions": {"X": {"type": "string"}}}`,
+		`{"definitions": {"X": {"type": "object", "properties": {"y": {"$ref": "#/definitions/Y"}}}}}`,
+		`{"definitions": {"X": {"type": "object", "properties": {"y": {"type": "null"}}}}}`,
+		`{"definitions": {"X": {"type": "object", "x-kind": "x"}}}`,
+		`{"definitions": {"X": {"type": "integer", "enum": ["1"]}}}`,
+	} {
+		_, err := Generate("x", "test.json", []byte(src))
+		assert.Error(s.T(), err, src)
+	}
+}
+
+var (
+	tsInterface = regexp.MustCompile(`^export interface (\w+)`)
+	tsFieldLine = regexp.MustCompile(`^readonly '?([\w#]+)'?(\??): ([^;]+);\s*(?://\s*(.*))?$`)
+	tsGeneric   = regexp.MustCompile(`<[^>]*>`)
+	jsonTsName  = regexp.MustCompile(`T\d*$`)
+)
+
+func tsField(typ string, description string) string {
+	if description == "" {
+		return typ
+	}
+	return typ + " // " + description
+}
+
+// tsFields reads the fields of the interfaces in the TypeScript schema as
+// name: type[?] // description.
+func (s *GeneratorSuite) tsFields(fnames ...string) map[string]map[string]string {
+	ret := map[string]map[string]string{}
+	var fields map[string]string
+	for _, fname := range fnames {
+		src, err := os.ReadFile(fname)
+		assert.NoError(s.T(), err)
+		for _, line := range strings.Split(string(src), "\n") {
+			line = strings.TrimSpace(line)
+			if m := tsInterface.FindStringSubmatch(line); m != nil {
+				fields = map[string]string{}
+				ret[m[1]] = fields
+			} else if m := tsFieldLine.FindStringSubmatch(line); m != nil {
+				typ := tsGeneric.ReplaceAllString(m[3], "")
+				fields[m[1]] = tsField(typ+m[2], strings.TrimSpace(m[4]))
+			}
+		}
+	}
+	return ret
+}
+
+// jsonTsType is the TypeScript type of prop, definitions are named like
+// their interface without the T suffix.
+func jsonTsType(prop *schema, defs map[string]*schema) string {
+	switch {
+	case prop.Ref != "":
+		name := strings.TrimPrefix(prop.Ref, "#/definitions/")
+		if def := defs[name]; len(def.Enum) == 1 {
+			return "'" + def.Enum[0] + "'"
+		}
+		return jsonTsName.ReplaceAllString(name, "")
+	case prop.Type == "array":
+		return jsonTsType(prop.Items, defs) + "[]"
+	}
+	return prop.Type
+}
+
+// TestTypeScriptInSync fails if schema/*.ts and envelope.schema.json
+// disagree on a field, its type or its description.
+func (s *GeneratorSuite) TestTypeScriptInSync() {
+	ts := s.tsFields("../../schema/envelope.ts", "../../schema/payload.ts", "../../schema/sample.ts")
+	src, err := os.ReadFile("../../schema/envelope.schema.json")
+	assert.NoError(s.T(), err)
+	js := schema{}
+	assert.NoError(s.T(), json.Unmarshal(src, &js))
+	names := []string{}
+	for name, def := range js.Definitions {
+		if def.Type != "object" || def.Properties == nil {
+			continue
+		}
+		tsName := jsonTsName.ReplaceAllString(name, "")
+		if tsName == "" {
+			tsName = name
+		}
+		names = append(names, tsName)
+		required := map[string]bool{}
+		for _, prop := range def.Required {
+			required[prop] = true
+		}
+		fields := map[string]string{}
+		for prop, ps := range def.Properties {
+			typ := jsonTsType(ps, js.Definitions)
+			if typ == "object" {
+				// the generic data of a payload
+				typ = "T"
+			}
+			if !required[prop] {
+				typ += "?"
+			}
+			description := ps.Description
+			if ps.Ref != "" && description == "" {
+				description = js.Definitions[strings.TrimPrefix(ps.Ref, "#/definitions/")].Description
+			}
+			fields[prop] = tsField(typ, description)
+		}
+		assert.Equal(s.T(), ts[tsName], fields, name)
+	}
+	for name := range ts {
+		assert.Contains(s.T(), names, name, "no definition of the interface")
+	}
+}
+
+func TestGeneratorSuite(t *testing.T) {
+	suite.Run(t, new(GeneratorSuite))
+}
@@ -0,0 +1,48 @@
+// c5gen generates the Go types of payload kinds from JSON Schema
+// definitions: a struct with Marshal, Unmarshal*, ToDict and FromDict* per
+// object, string enums with From*/To* and the registration of every
+// definition with "x-kind" in the kind registry of the envelope package.
+//
+//	//go:generate go run github.com/mabels/c5-envelope/cmd/c5gen -package kinds -o kinds.go kinds.schema.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	pkg := flag.String("package", "", "package name of the generated file")
+	out := flag.String("o", "", "output file, default stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: c5gen -package name [-o file] schema.json\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *pkg == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(*pkg, flag.Arg(0), *out); err != nil {
+		fmt.Fprintln(os.Stderr, "c5gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(pkg string, fname string, out string) error {
+	src, err := os.ReadFile(fname)
+	if err != nil {
+		return err
+	}
+	code, err := Generate(pkg, filepath.Base(fname), src)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(out, code, 0644)
+}
@@ -4,13 +4,17 @@ go 1.17
 
 require (
 	github.com/btcsuite/btcutil v1.0.2
+	github.com/fxamacker/cbor/v2 v2.5.0
+	github.com/mabels/object-graph-streamer v0.0.2-0.20211213204301-a74d76202d15
 	github.com/stretchr/testify v1.7.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
-	github.com/mabels/object-graph-streamer v0.0.2-0.20211213204301-a74d76202d15 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.1.0 // indirect
-	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
 )
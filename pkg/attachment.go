@@ -0,0 +1,289 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// AttachmentsKey lists the attachments in the data of an envelope:
+// {"_attachments":[{"hash":"...","mediaType":"image/png","size":1234}]}
+// so the data hash commits to their content hashes but not the content.
+const AttachmentsKey = "_attachments"
+
+var (
+	ErrBlobNotFound   = errors.New("blob not found")
+	ErrAttachmentHash = errors.New("attachment hash mismatch")
+)
+
+type Attachment struct {
+	// Hash is the base58 SHA-256 of the content
+	Hash      string `json:"hash"`
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Name      string `json:"name,omitempty"`
+}
+
+func (a *Attachment) ToDict() map[string]interface{} {
+	ret := map[string]interface{}{
+		"hash":      a.Hash,
+		"mediaType": a.MediaType,
+		"size":      a.Size,
+	}
+	if a.Name != "" {
+		ret["name"] = a.Name
+	}
+	return ret
+}
+
+func FromDictAttachment(m map[string]interface{}) (*Attachment, error) {
+	hash, ok := m["hash"].(string)
+	if !ok || hash == "" {
+		return nil, fmt.Errorf("attachment without hash")
+	}
+	size, ok := asInt(m["size"])
+	if !ok {
+		return nil, fmt.Errorf("attachment %s without size", hash)
+	}
+	att := &Attachment{Hash: hash, Size: int64(size)}
+	att.MediaType, _ = m["mediaType"].(string)
+	att.Name, _ = m["name"].(string)
+	return att, nil
+}
+
+// BlobStore keeps attachment content by its hash.
+type BlobStore interface {
+	// Put stores the content of r and returns its hash and size
+	Put(r io.Reader) (string, int64, error)
+	Get(hash string) (io.ReadCloser, error)
+	Has(hash string) (bool, error)
+}
+
+type MemoryBlobStore struct {
+	lock  sync.RWMutex
+	blobs map[string][]byte
+}
+
+func NewMemoryBlobStore() *MemoryBlobStore {
+	return &MemoryBlobStore{blobs: map[string][]byte{}}
+}
+
+func (m *MemoryBlobStore) Put(r io.Reader) (string, int64, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", 0, err
+	}
+	sum := sha256.Sum256(data)
+	hash := base58.Encode(sum[:])
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.blobs[hash] = data
+	return hash, int64(len(data)), nil
+}
+
+func (m *MemoryBlobStore) Get(hash string) (io.ReadCloser, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	data, ok := m.blobs[hash]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, hash)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *MemoryBlobStore) Has(hash string) (bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	_, ok := m.blobs[hash]
+	return ok, nil
+}
+
+// FileBlobStore is the default BlobStore, every blob is a file named by
+// its hash, content is streamed and never held in memory.
+type FileBlobStore struct {
+	dir string
+}
+
+func NewFileBlobStore(dir string) (*FileBlobStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &FileBlobStore{dir: dir}, nil
+}
+
+// DefaultBlobStore is a FileBlobStore in the user cache directory.
+func DefaultBlobStore() (*FileBlobStore, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileBlobStore(filepath.Join(dir, "c5-envelope", "blobs"))
+}
+
+func (f *FileBlobStore) blobPath(hash string) (string, error) {
+	if hash == "" || strings.ContainsAny(hash, `/\.`) {
+		return "", fmt.Errorf("%w: invalid hash %q", ErrBlobNotFound, hash)
+	}
+	return filepath.Join(f.dir, hash), nil
+}
+
+func (f *FileBlobStore) Put(r io.Reader) (string, int64, error) {
+	tmp, err := os.CreateTemp(f.dir, ".put-")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp.Name())
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	hash := base58.Encode(h.Sum(nil))
+	fname, _ := f.blobPath(hash)
+	if err := os.Rename(tmp.Name(), fname); err != nil {
+		return "", 0, err
+	}
+	return hash, size, nil
+}
+
+func (f *FileBlobStore) Get(hash string) (io.ReadCloser, error) {
+	fname, err := f.blobPath(hash)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fname)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, hash)
+	}
+	return file, err
+}
+
+func (f *FileBlobStore) Has(hash string) (bool, error) {
+	fname, err := f.blobPath(hash)
+	if err != nil {
+		return false, nil
+	}
+	_, err = os.Stat(fname)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Attach stores the content of r in store and returns the attachment to
+// pass in SimpleEnvelopeProps.Attachments.
+func Attach(store BlobStore, r io.Reader, mediaType string, name string) (*Attachment, error) {
+	hash, size, err := store.Put(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Attachment{Hash: hash, MediaType: mediaType, Size: size, Name: name}, nil
+}
+
+// withAttachments returns a copy of pay listing atts sorted by hash.
+func withAttachments(pay PayloadT1, atts []Attachment) PayloadT1 {
+	ret := copyPayload(pay)
+	sorted := append([]Attachment{}, atts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Hash < sorted[j].Hash
+	})
+	list := make([]interface{}, len(sorted))
+	for idx := range sorted {
+		list[idx] = sorted[idx].ToDict()
+	}
+	ret.Data[AttachmentsKey] = list
+	return ret
+}
+
+// Attachments returns the attachments listed in env.
+func Attachments(env *EnvelopeT) ([]Attachment, error) {
+	list, ok := env.Data.Data[AttachmentsKey].([]interface{})
+	if !ok {
+		return []Attachment{}, nil
+	}
+	ret := make([]Attachment, 0, len(list))
+	for _, item := range list {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s entry:%T", AttachmentsKey, item)
+		}
+		att, err := FromDictAttachment(m)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, *att)
+	}
+	return ret, nil
+}
+
+type verifyingReader struct {
+	rc   io.ReadCloser
+	att  Attachment
+	hash hash.Hash
+	size int64
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.rc.Read(p)
+	v.hash.Write(p[:n])
+	v.size += int64(n)
+	if err == io.EOF {
+		if v.size != v.att.Size {
+			return n, fmt.Errorf("%w: %s size %d expected %d", ErrAttachmentHash, v.att.Hash, v.size, v.att.Size)
+		}
+		if hash := base58.Encode(v.hash.Sum(nil)); hash != v.att.Hash {
+			return n, fmt.Errorf("%w: %s got %s", ErrAttachmentHash, v.att.Hash, hash)
+		}
+	}
+	return n, err
+}
+
+func (v *verifyingReader) Close() error {
+	return v.rc.Close()
+}
+
+// OpenAttachment returns the content of att, reading it to the end fails
+// with ErrAttachmentHash if the stored blob doesn't match.
+func OpenAttachment(store BlobStore, att Attachment) (io.ReadCloser, error) {
+	rc, err := store.Get(att.Hash)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{rc: rc, att: att, hash: sha256.New()}, nil
+}
+
+// VerifyAttachments checks that every attachment of env is in store with
+// the listed hash and size.
+func VerifyAttachments(env *EnvelopeT, store BlobStore) error {
+	atts, err := Attachments(env)
+	if err != nil {
+		return err
+	}
+	for _, att := range atts {
+		rc, err := OpenAttachment(store, att)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(io.Discard, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package c5
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AttachmentSuite struct {
+	suite.Suite
+	store *FileBlobStore
+}
+
+func (s *AttachmentSuite) SetupTest() {
+	store, err := NewFileBlobStore(filepath.Join(s.T().TempDir(), "blobs"))
+	assert.NoError(s.T(), err)
+	s.store = store
+}
+
+func (s *AttachmentSuite) envelope(atts ...Attachment) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "upload",
+			Data: map[string]interface{}{"title": "report"},
+		},
+		Attachments:   atts,
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *AttachmentSuite) TestAttach() {
+	png, err := Attach(s.store, bytes.NewReader([]byte("not really a png")), "image/png", "logo.png")
+	assert.NoError(s.T(), err)
+	txt, err := Attach(s.store, strings.NewReader("hello"), "text/plain", "")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), int64(5), txt.Size)
+
+	se := s.envelope(*png, *txt)
+	assert.NotContains(s.T(), *se.AsJson(), "hello")
+	env, err := UnmarshalEnvelopeT([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), VerifyID(env))
+	atts, err := Attachments(env)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), atts, 2)
+	assert.ElementsMatch(s.T(), []Attachment{*png, *txt}, atts)
+	assert.NoError(s.T(), VerifyAttachments(env, s.store))
+
+	rc, err := OpenAttachment(s.store, *txt)
+	assert.NoError(s.T(), err)
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "hello", string(data))
+}
+
+func (s *AttachmentSuite) TestHashCommits() {
+	a, _ := Attach(s.store, strings.NewReader("a"), "text/plain", "")
+	b, _ := Attach(s.store, strings.NewReader("b"), "text/plain", "")
+	assert.NotEqual(s.T(), s.envelope(*a).AsEnvelope().ID, s.envelope(*b).AsEnvelope().ID)
+	// the order of the attachments does not matter
+	assert.Equal(s.T(), s.envelope(*a, *b).AsEnvelope().ID, s.envelope(*b, *a).AsEnvelope().ID)
+}
+
+func (s *AttachmentSuite) TestTamperedBlob() {
+	att, err := Attach(s.store, strings.NewReader("original"), "text/plain", "")
+	assert.NoError(s.T(), err)
+	env := s.envelope(*att).AsEnvelope()
+	fname, _ := s.store.blobPath(att.Hash)
+	assert.NoError(s.T(), os.WriteFile(fname, []byte("modified"), 0600))
+	assert.True(s.T(), errors.Is(VerifyAttachments(env, s.store), ErrAttachmentHash))
+}
+
+func (s *AttachmentSuite) TestMissingBlob() {
+	att, err := Attach(NewMemoryBlobStore(), strings.NewReader("elsewhere"), "text/plain", "")
+	assert.NoError(s.T(), err)
+	env := s.envelope(*att).AsEnvelope()
+	assert.True(s.T(), errors.Is(VerifyAttachments(env, s.store), ErrBlobNotFound))
+	ok, err := s.store.Has(att.Hash)
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), ok)
+}
+
+func TestAttachmentSuite(t *testing.T) {
+	suite.Run(t, new(AttachmentSuite))
+}
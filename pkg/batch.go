@@ -0,0 +1,133 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// BatchKind is the kind of the envelope which commits to a batch.
+const BatchKind = "c5.batch"
+
+var ErrInclusionProof = errors.New("inclusion proof invalid")
+
+// Batch commits to many envelopes with one Merkle root over their data
+// hashes, so a single (signed) batch envelope covers all of them.
+type Batch struct {
+	envelopes []*SimpleEnvelope
+	leaves    [][]byte
+}
+
+func NewBatch() *Batch {
+	return &Batch{}
+}
+
+func dataHashLeaf(hash string) []byte {
+	return merkleLeafHash(base58.Decode(hash))
+}
+
+// Add appends se and returns its index in the batch.
+func (b *Batch) Add(se *SimpleEnvelope) int {
+	b.envelopes = append(b.envelopes, se)
+	b.leaves = append(b.leaves, dataHashLeaf(se.DataHash()))
+	return len(b.envelopes) - 1
+}
+
+func (b *Batch) Len() int {
+	return len(b.envelopes)
+}
+
+func (b *Batch) Envelopes() []*SimpleEnvelope {
+	return b.envelopes
+}
+
+func (b *Batch) Root() string {
+	return base58.Encode(merkleRoot(b.leaves))
+}
+
+// Seal returns the batch envelope, props provides everything but the data
+// which is {"root": ..., "size": ...} of kind BatchKind.
+func (b *Batch) Seal(props SimpleEnvelopeProps) *SimpleEnvelope {
+	props.Data = PayloadT1{
+		Kind: BatchKind,
+		Data: map[string]interface{}{
+			"root": b.Root(),
+			"size": len(b.leaves),
+		},
+	}
+	return NewSimpleEnvelope(&props)
+}
+
+type InclusionProof struct {
+	Index int      `json:"index"`
+	Size  int      `json:"size"`
+	Leaf  string   `json:"leaf"`
+	Root  string   `json:"root"`
+	Path  []string `json:"path"`
+}
+
+// Proof returns the inclusion proof of the envelope at idx.
+func (b *Batch) Proof(idx int) (*InclusionProof, error) {
+	if idx < 0 || idx >= len(b.envelopes) {
+		return nil, fmt.Errorf("batch index out of range:%d", idx)
+	}
+	path := merkleInclusionPath(b.leaves, idx)
+	proof := &InclusionProof{
+		Index: idx,
+		Size:  len(b.leaves),
+		Leaf:  b.envelopes[idx].DataHash(),
+		Root:  b.Root(),
+		Path:  make([]string, len(path)),
+	}
+	for i, p := range path {
+		proof.Path[i] = base58.Encode(p)
+	}
+	return proof, nil
+}
+
+func (p *InclusionProof) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func UnmarshalInclusionProof(data []byte) (*InclusionProof, error) {
+	proof := InclusionProof{}
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// verify checks the proof against its own Root; leafHash is the merkle
+// leaf hash of the proven entry.
+func (p *InclusionProof) verify(leafHash []byte) error {
+	path := make([][]byte, len(p.Path))
+	for i, h := range p.Path {
+		path[i] = base58.Decode(h)
+	}
+	if !verifyMerkleInclusion(leafHash, p.Index, p.Size, path, base58.Decode(p.Root)) {
+		return fmt.Errorf("%w: index %d of %d", ErrInclusionProof, p.Index, p.Size)
+	}
+	return nil
+}
+
+// VerifyBatchInclusion checks offline that env is part of the batch
+// committed by batchEnv: the data hash of env is the proven leaf and the
+// proof leads to the root of batchEnv.
+func VerifyBatchInclusion(env *EnvelopeT, proof *InclusionProof, batchEnv *EnvelopeT) error {
+	if batchEnv.Data.Kind != BatchKind {
+		return fmt.Errorf("%w: not a %s envelope", ErrInclusionProof, BatchKind)
+	}
+	if root, _ := batchEnv.Data.Data["root"].(string); root != proof.Root {
+		return fmt.Errorf("%w: root %s is not the batch root", ErrInclusionProof, proof.Root)
+	}
+	if size := batchEnv.Data.Data["size"]; fmt.Sprint(size) != fmt.Sprint(proof.Size) {
+		return fmt.Errorf("%w: size %d is not the batch size %v", ErrInclusionProof, proof.Size, size)
+	}
+	hash := NewSimpleEnvelopeFromEnvelopeT(env, nil).DataHash()
+	if hash != proof.Leaf {
+		return fmt.Errorf("%w: data hash %s is not the leaf %s", ErrInclusionProof, hash, proof.Leaf)
+	}
+	return proof.verify(dataHashLeaf(hash))
+}
@@ -0,0 +1,78 @@
+package c5
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type BatchSuite struct {
+	suite.Suite
+}
+
+func (s *BatchSuite) batch(n int) *Batch {
+	batch := NewBatch()
+	for i := 0; i < n; i++ {
+		batch.Add(NewSimpleEnvelope(&SimpleEnvelopeProps{
+			Src: "test case",
+			Data: PayloadT1{
+				Kind: "kind",
+				Data: map[string]interface{}{"y": i},
+			},
+			TimeGenerator: mtimer,
+		}))
+	}
+	return batch
+}
+
+func (s *BatchSuite) TestSealAndProve() {
+	batch := s.batch(7)
+	assert.Equal(s.T(), 7, batch.Len())
+	sealed := batch.Seal(SimpleEnvelopeProps{Src: "batcher", TimeGenerator: mtimer})
+	ref, err := UnmarshalEnvelopeT([]byte(*sealed.AsJson()))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), BatchKind, ref.Data.Kind)
+	assert.Equal(s.T(), batch.Root(), ref.Data.Data["root"])
+
+	for idx, se := range batch.Envelopes() {
+		proof, err := batch.Proof(idx)
+		assert.NoError(s.T(), err)
+		b, err := proof.Marshal()
+		assert.NoError(s.T(), err)
+		proof, err = UnmarshalInclusionProof(b)
+		assert.NoError(s.T(), err)
+		env, err := UnmarshalEnvelopeT([]byte(*se.AsJson()))
+		assert.NoError(s.T(), err)
+		assert.NoError(s.T(), VerifyBatchInclusion(env, proof, ref))
+	}
+}
+
+func (s *BatchSuite) TestWrongEnvelope() {
+	batch := s.batch(4)
+	sealed := batch.Seal(SimpleEnvelopeProps{Src: "batcher"}).AsEnvelope()
+	proof, err := batch.Proof(1)
+	assert.NoError(s.T(), err)
+	err = VerifyBatchInclusion(batch.Envelopes()[2].AsEnvelope(), proof, sealed)
+	assert.True(s.T(), errors.Is(err, ErrInclusionProof))
+
+	proof.Leaf = batch.Envelopes()[2].DataHash()
+	err = VerifyBatchInclusion(batch.Envelopes()[2].AsEnvelope(), proof, sealed)
+	assert.True(s.T(), errors.Is(err, ErrInclusionProof))
+}
+
+func (s *BatchSuite) TestOtherBatch() {
+	batch := s.batch(4)
+	other := s.batch(5).Seal(SimpleEnvelopeProps{Src: "batcher"}).AsEnvelope()
+	proof, err := batch.Proof(0)
+	assert.NoError(s.T(), err)
+	err = VerifyBatchInclusion(batch.Envelopes()[0].AsEnvelope(), proof, other)
+	assert.True(s.T(), errors.Is(err, ErrInclusionProof))
+	_, err = batch.Proof(4)
+	assert.Error(s.T(), err)
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
@@ -0,0 +1,216 @@
+package c5
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// ChainPrevKey links an envelope to its predecessor in a Chain:
+// {"_prev":{"seq":2,"id":"<id of seq 1>","digest":"<digest of seq 1>"}}.
+// The first envelope only carries {"seq":0}.
+const ChainPrevKey = "_prev"
+
+var (
+	ErrChainGap      = errors.New("chain gap")
+	ErrChainOrder    = errors.New("chain reordered")
+	ErrChainTampered = errors.New("chain tampered")
+)
+
+type ChainLink struct {
+	Seq    int
+	ID     string
+	Digest string
+}
+
+// EnvelopeDigest is the base58 SHA-256 of the canonical JSON of env, it
+// covers the header fields which the ID doesn't.
+func EnvelopeDigest(env *EnvelopeT) string {
+	sum := sha256.Sum256([]byte(canonicalJson(env)))
+	return base58.Encode(sum[:])
+}
+
+func asInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), float64(int(n)) == n
+	case json.Number:
+		i, err := n.Int64()
+		return int(i), err == nil
+	}
+	return 0, false
+}
+
+// ChainLinkOf reads the link of a chained envelope.
+func ChainLinkOf(env *EnvelopeT) (*ChainLink, error) {
+	prev, ok := env.Data.Data[ChainPrevKey].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no %s", ErrChainTampered, env.ID, ChainPrevKey)
+	}
+	seq, ok := asInt(prev["seq"])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no seq", ErrChainTampered, env.ID)
+	}
+	link := &ChainLink{Seq: seq}
+	link.ID, _ = prev["id"].(string)
+	link.Digest, _ = prev["digest"].(string)
+	return link, nil
+}
+
+func (l *ChainLink) toDict() map[string]interface{} {
+	ret := map[string]interface{}{"seq": l.Seq}
+	if l.Seq > 0 {
+		ret["id"] = l.ID
+		ret["digest"] = l.Digest
+	}
+	return ret
+}
+
+// Chain is an append-only file of envelopes, one JSON per line, where each
+// envelope references its predecessor.
+type Chain struct {
+	lock sync.Mutex
+	file *os.File
+	next ChainLink
+}
+
+// OpenChain opens or creates the chain file; an existing file is verified
+// completely before new envelopes are appended.
+func OpenChain(fname string) (*Chain, error) {
+	file, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	chain := &Chain{file: file}
+	last, cnt, err := verifyChain(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	chain.next.Seq = cnt
+	if last != nil {
+		chain.next.ID = last.ID
+		chain.next.Digest = EnvelopeDigest(last)
+	}
+	return chain, nil
+}
+
+// Append links props.Data to the last envelope, writes and syncs it. The
+// data of props isn't modified.
+func (c *Chain) Append(props SimpleEnvelopeProps) (*SimpleEnvelope, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var pay PayloadT1
+	switch v := props.Data.(type) {
+	case map[string]interface{}:
+		if err := FromDictPayloadT1(v, &pay); err != nil {
+			return nil, err
+		}
+	case PayloadT1:
+		pay = v
+	case PayloadT:
+		pay = PayloadT1(v)
+	default:
+		return nil, fmt.Errorf("unhandled Type:%T", v)
+	}
+	pay = copyPayload(pay)
+	pay.Data[ChainPrevKey] = c.next.toDict()
+	props.ID = ""
+	props.JsonProp = nil
+	props.Data = pay
+	se, err := BuildSimpleEnvelope(&props)
+	if err != nil {
+		return nil, err
+	}
+	line := *se.AsJson() + "\n"
+	if _, err := c.file.WriteString(line); err != nil {
+		return nil, err
+	}
+	if err := c.file.Sync(); err != nil {
+		return nil, err
+	}
+	env := se.AsEnvelope()
+	c.next = ChainLink{Seq: c.next.Seq + 1, ID: env.ID, Digest: EnvelopeDigest(env)}
+	return se, nil
+}
+
+// Head is the link the next envelope will get, anchoring (e.g. signing)
+// it protects the header of the last envelope as well.
+func (c *Chain) Head() ChainLink {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.next
+}
+
+func (c *Chain) Close() error {
+	return c.file.Close()
+}
+
+func verifyChain(r io.Reader) (*EnvelopeT, int, error) {
+	reader := bufio.NewReader(r)
+	var prev *EnvelopeT
+	idx := 0
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			env, perr := UnmarshalEnvelopeTUseNumber(line)
+			if perr != nil {
+				return prev, idx, fmt.Errorf("envelope %d: %w: %v", idx, ErrChainTampered, perr)
+			}
+			if verr := verifyChainLink(env, prev, idx); verr != nil {
+				return prev, idx, fmt.Errorf("envelope %d: %w", idx, verr)
+			}
+			prev = env
+			idx++
+		}
+		if err == io.EOF {
+			return prev, idx, nil
+		}
+		if err != nil {
+			return prev, idx, err
+		}
+	}
+}
+
+func verifyChainLink(env *EnvelopeT, prev *EnvelopeT, idx int) error {
+	if err := VerifyID(env); err != nil {
+		return fmt.Errorf("%w: %v", ErrChainTampered, err)
+	}
+	link, err := ChainLinkOf(env)
+	if err != nil {
+		return err
+	}
+	switch {
+	case link.Seq > idx:
+		return fmt.Errorf("%w: seq %d expected %d", ErrChainGap, link.Seq, idx)
+	case link.Seq < idx:
+		return fmt.Errorf("%w: seq %d expected %d", ErrChainOrder, link.Seq, idx)
+	case prev == nil:
+		return nil
+	case link.ID != prev.ID:
+		return fmt.Errorf("%w: prev id %s expected %s", ErrChainTampered, link.ID, prev.ID)
+	case link.Digest != EnvelopeDigest(prev):
+		return fmt.Errorf("%w: prev digest of %s does not match", ErrChainTampered, prev.ID)
+	}
+	return nil
+}
+
+// VerifyChain reads a chain log and checks every link; gaps, reordering
+// and modified envelopes are reported with the index of the first bad
+// envelope. It returns the number of valid envelopes.
+func VerifyChain(r io.Reader) (int, error) {
+	_, cnt, err := verifyChain(r)
+	return cnt, err
+}
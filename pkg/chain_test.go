@@ -0,0 +1,185 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ChainSuite struct {
+	suite.Suite
+	fname string
+}
+
+func (s *ChainSuite) SetupTest() {
+	s.fname = filepath.Join(s.T().TempDir(), "chain.ndjson")
+}
+
+func (s *ChainSuite) props(i int) SimpleEnvelopeProps {
+	return SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "event",
+			Data: map[string]interface{}{"i": i},
+		},
+		TimeGenerator: mtimer,
+	}
+}
+
+func (s *ChainSuite) appendN(n int) []*SimpleEnvelope {
+	chain, err := OpenChain(s.fname)
+	assert.NoError(s.T(), err)
+	defer chain.Close()
+	ret := []*SimpleEnvelope{}
+	for i := 0; i < n; i++ {
+		se, err := chain.Append(s.props(i))
+		assert.NoError(s.T(), err)
+		ret = append(ret, se)
+	}
+	return ret
+}
+
+func (s *ChainSuite) lines() []string {
+	data, err := os.ReadFile(s.fname)
+	assert.NoError(s.T(), err)
+	return strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+}
+
+func (s *ChainSuite) verify(lines []string) (int, error) {
+	return VerifyChain(strings.NewReader(strings.Join(lines, "\n") + "\n"))
+}
+
+func (s *ChainSuite) TestAppend() {
+	envs := s.appendN(3)
+	first, err := ChainLinkOf(envs[0].AsEnvelope())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), &ChainLink{Seq: 0}, first)
+	second, err := ChainLinkOf(envs[1].AsEnvelope())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), &ChainLink{
+		Seq:    1,
+		ID:     envs[0].AsEnvelope().ID,
+		Digest: EnvelopeDigest(envs[0].AsEnvelope()),
+	}, second)
+
+	cnt, err := s.verify(s.lines())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, cnt)
+}
+
+func (s *ChainSuite) TestAppendDict() {
+	chain, err := OpenChain(s.fname)
+	assert.NoError(s.T(), err)
+	defer chain.Close()
+	props := s.props(0)
+	props.Data = map[string]interface{}{"kind": "event", "data": map[string]interface{}{"i": 0}}
+	se, err := chain.Append(props)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "event", se.AsEnvelope().Data.Kind)
+
+	props.Data = map[string]interface{}{"kind": "event"}
+	_, err = chain.Append(props)
+	assert.True(s.T(), errors.Is(err, ErrDictMissing))
+
+	props = s.props(1)
+	props.SelectiveDisclosure = &SelectiveDisclosureProps{Paths: []string{"missing"}}
+	_, err = chain.Append(props)
+	assert.True(s.T(), errors.Is(err, ErrDisclosurePath))
+	assert.Equal(s.T(), 1, len(s.lines()))
+}
+
+func (s *ChainSuite) TestLargeIntegers() {
+	chain, err := OpenChain(s.fname)
+	assert.NoError(s.T(), err)
+	for _, big := range []interface{}{int64(9007199254740993), json.Number("9007199254740993")} {
+		props := s.props(0)
+		props.Data = PayloadT1{Kind: "event", Data: map[string]interface{}{"i": big}}
+		_, err = chain.Append(props)
+		assert.NoError(s.T(), err)
+	}
+	assert.NoError(s.T(), chain.Close())
+
+	chain, err = OpenChain(s.fname)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), chain.Close())
+	cnt, err := s.verify(s.lines())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 2, cnt)
+	assert.Contains(s.T(), s.lines()[1], `"i":9007199254740993`)
+}
+
+func (s *ChainSuite) TestReopen() {
+	envs := s.appendN(2)
+	chain, err := OpenChain(s.fname)
+	assert.NoError(s.T(), err)
+	defer chain.Close()
+	assert.Equal(s.T(), ChainLink{
+		Seq:    2,
+		ID:     envs[1].AsEnvelope().ID,
+		Digest: EnvelopeDigest(envs[1].AsEnvelope()),
+	}, chain.Head())
+	_, err = chain.Append(s.props(2))
+	assert.NoError(s.T(), err)
+	cnt, err := s.verify(s.lines())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), 3, cnt)
+}
+
+func (s *ChainSuite) TestGap() {
+	s.appendN(4)
+	lines := s.lines()
+	cnt, err := s.verify(append(lines[:2:2], lines[3:]...))
+	assert.True(s.T(), errors.Is(err, ErrChainGap))
+	assert.Equal(s.T(), 2, cnt)
+}
+
+func (s *ChainSuite) TestReorder() {
+	s.appendN(4)
+	lines := s.lines()
+	lines[1], lines[2] = lines[2], lines[1]
+	cnt, err := s.verify(lines)
+	assert.True(s.T(), errors.Is(err, ErrChainGap))
+	assert.Equal(s.T(), 1, cnt)
+
+	lines = s.lines()
+	cnt, err = s.verify(append(lines, lines[1]))
+	assert.True(s.T(), errors.Is(err, ErrChainOrder))
+	assert.Equal(s.T(), 4, cnt)
+}
+
+func (s *ChainSuite) TestTamperedData() {
+	s.appendN(3)
+	lines := s.lines()
+	lines[1] = strings.Replace(lines[1], `"i":1`, `"i":7`, 1)
+	cnt, err := s.verify(lines)
+	assert.True(s.T(), errors.Is(err, ErrChainTampered))
+	assert.Equal(s.T(), 1, cnt)
+}
+
+func (s *ChainSuite) TestTamperedHeader() {
+	s.appendN(3)
+	lines := s.lines()
+	lines[1] = strings.Replace(lines[1], `"src":"test case"`, `"src":"forged"`, 1)
+	cnt, err := s.verify(lines)
+	assert.True(s.T(), errors.Is(err, ErrChainTampered))
+	assert.Equal(s.T(), 2, cnt)
+}
+
+func (s *ChainSuite) TestOpenTampered() {
+	s.appendN(2)
+	lines := s.lines()
+	lines[0] = strings.Replace(lines[0], `"i":0`, `"i":7`, 1)
+	assert.NoError(s.T(), os.WriteFile(s.fname, []byte(strings.Join(lines, "\n")+"\n"), 0644))
+	_, err := OpenChain(s.fname)
+	assert.True(s.T(), errors.Is(err, ErrChainTampered))
+}
+
+func TestChainSuite(t *testing.T) {
+	suite.Run(t, new(ChainSuite))
+}
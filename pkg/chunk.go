@@ -0,0 +1,299 @@
+package c5
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/btcsuite/btcutil/base58"
+	ogs "github.com/mabels/object-graph-streamer"
+)
+
+const (
+	// ChunkKind envelopes carry {"transfer","seq","hash","data"}, data is
+	// the base64 of the chunk and hash the base58 SHA-256 of it.
+	ChunkKind = "c5.chunk"
+	// TransferKind closes a transfer with {"transfer","chunks","size","hash"}
+	// where hash covers the whole payload.
+	TransferKind = "c5.transfer"
+
+	DefaultChunkSize = 1024 * 1024
+	// DefaultMaxPending bytes and DefaultMaxWindow chunks are held back by
+	// a Reassembler at most.
+	DefaultMaxPending = 64 * DefaultChunkSize
+	DefaultMaxWindow  = 1024
+)
+
+var (
+	ErrChunkInvalid = errors.New("chunk invalid")
+	ErrTransferHash = errors.New("transfer hash mismatch")
+	ErrChunkWindow  = errors.New("chunk window exceeded")
+)
+
+type ChunkerProps struct {
+	Src string
+	Dst []string
+	// TransferID is shared by all envelopes of one transfer, a random
+	// one is used if empty
+	TransferID    string
+	ChunkSize     int
+	JsonProp      *ogs.JsonProps
+	TimeGenerator TimeGenerator
+}
+
+// Chunker splits a payload into ChunkKind envelopes without holding more
+// than one chunk in memory.
+type Chunker struct {
+	props ChunkerProps
+}
+
+func NewChunker(props ChunkerProps) *Chunker {
+	if props.TransferID == "" {
+		props.TransferID = randomSalt()
+	}
+	if props.ChunkSize <= 0 {
+		props.ChunkSize = DefaultChunkSize
+	}
+	return &Chunker{props: props}
+}
+
+func (c *Chunker) TransferID() string {
+	return c.props.TransferID
+}
+
+func (c *Chunker) envelope(kind string, data map[string]interface{}) *SimpleEnvelope {
+	data["transfer"] = c.props.TransferID
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           c.props.Src,
+		Dst:           c.props.Dst,
+		Data:          PayloadT1{Kind: kind, Data: data},
+		JsonProp:      c.props.JsonProp,
+		TimeGenerator: c.props.TimeGenerator,
+	})
+}
+
+// Split reads r to the end and passes every chunk envelope and finally the
+// TransferKind envelope to emit.
+func (c *Chunker) Split(r io.Reader, emit func(*SimpleEnvelope) error) error {
+	total := sha256.New()
+	buf := make([]byte, c.props.ChunkSize)
+	seq := 0
+	size := 0
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := buf[:n]
+			total.Write(chunk)
+			sum := sha256.Sum256(chunk)
+			eerr := emit(c.envelope(ChunkKind, map[string]interface{}{
+				"seq":  seq,
+				"hash": base58.Encode(sum[:]),
+				"data": base64.StdEncoding.EncodeToString(chunk),
+			}))
+			if eerr != nil {
+				return eerr
+			}
+			seq++
+			size += n
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return emit(c.envelope(TransferKind, map[string]interface{}{
+		"chunks": seq,
+		"size":   size,
+		"hash":   base58.Encode(total.Sum(nil)),
+	}))
+}
+
+type transferInfo struct {
+	chunks int
+	size   int64
+	hash   string
+}
+
+// Reassembler writes the chunks of one transfer in order to w. Chunks may
+// arrive in any order, the ones ahead of the next expected chunk are held
+// back until the gap is filled. A chunk more than MaxWindow ahead or one
+// which would hold back more than MaxPending bytes is an ErrChunkWindow,
+// zero or less is no limit.
+type Reassembler struct {
+	MaxPending int64
+	MaxWindow  int
+
+	w           io.Writer
+	transfer    string
+	next        int
+	pending     map[int][]byte
+	pendingSize int64
+	total       hash.Hash
+	size        int64
+	info        *transferInfo
+	done        bool
+}
+
+func NewReassembler(w io.Writer) *Reassembler {
+	return &Reassembler{
+		MaxPending: DefaultMaxPending,
+		MaxWindow:  DefaultMaxWindow,
+		w:          w,
+		pending:    map[int][]byte{},
+		total:      sha256.New(),
+	}
+}
+
+func (r *Reassembler) TransferID() string {
+	return r.transfer
+}
+
+func (r *Reassembler) Complete() bool {
+	return r.done
+}
+
+// Missing returns the sequence numbers not received yet, it is only known
+// after the TransferKind envelope arrived.
+func (r *Reassembler) Missing() []int {
+	ret := []int{}
+	if r.info == nil {
+		return ret
+	}
+	for seq := r.next; seq < r.info.chunks; seq++ {
+		if _, ok := r.pending[seq]; !ok {
+			ret = append(ret, seq)
+		}
+	}
+	return ret
+}
+
+func chunkData(env *EnvelopeT) (int, []byte, error) {
+	seq, ok := asInt(env.Data.Data["seq"])
+	if !ok || seq < 0 {
+		return 0, nil, fmt.Errorf("%w: %s has no seq", ErrChunkInvalid, env.ID)
+	}
+	str, _ := env.Data.Data["data"].(string)
+	chunk, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: seq %d: %v", ErrChunkInvalid, seq, err)
+	}
+	sum := sha256.Sum256(chunk)
+	if hash, _ := env.Data.Data["hash"].(string); hash != base58.Encode(sum[:]) {
+		return 0, nil, fmt.Errorf("%w: seq %d hash mismatch", ErrChunkInvalid, seq)
+	}
+	return seq, chunk, nil
+}
+
+func transferData(env *EnvelopeT) (*transferInfo, error) {
+	chunks, ok := asInt(env.Data.Data["chunks"])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no chunks", ErrChunkInvalid, env.ID)
+	}
+	size, ok := asInt(env.Data.Data["size"])
+	if !ok {
+		return nil, fmt.Errorf("%w: %s has no size", ErrChunkInvalid, env.ID)
+	}
+	hash, _ := env.Data.Data["hash"].(string)
+	return &transferInfo{chunks: chunks, size: int64(size), hash: hash}, nil
+}
+
+// Add takes the next envelope of the transfer and returns true once the
+// payload is complete and its hash verified. Duplicates are ignored.
+func (r *Reassembler) Add(env *EnvelopeT) (bool, error) {
+	if err := VerifyID(env); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrChunkInvalid, err)
+	}
+	transfer, _ := env.Data.Data["transfer"].(string)
+	if r.transfer == "" {
+		r.transfer = transfer
+	}
+	if transfer != r.transfer {
+		return false, fmt.Errorf("%w: transfer %s expected %s", ErrChunkInvalid, transfer, r.transfer)
+	}
+	switch env.Data.Kind {
+	case ChunkKind:
+		seq, chunk, err := chunkData(env)
+		if err != nil {
+			return false, err
+		}
+		if r.info != nil && seq >= r.info.chunks {
+			return false, fmt.Errorf("%w: seq %d of %d chunks", ErrChunkInvalid, seq, r.info.chunks)
+		}
+		if err := r.hold(seq, chunk); err != nil {
+			return false, err
+		}
+		if err := r.flush(); err != nil {
+			return false, err
+		}
+	case TransferKind:
+		info, err := transferData(env)
+		if err != nil {
+			return false, err
+		}
+		if info.chunks < r.next {
+			return false, fmt.Errorf("%w: %d chunks but got %d", ErrChunkInvalid, info.chunks, r.next)
+		}
+		r.info = info
+	default:
+		return false, fmt.Errorf("%w: unknown kind %s", ErrChunkInvalid, env.Data.Kind)
+	}
+	return r.finish()
+}
+
+// hold keeps chunk until flush writes it, the next expected chunk doesn't
+// count against the limits.
+func (r *Reassembler) hold(seq int, chunk []byte) error {
+	if _, dup := r.pending[seq]; dup || seq < r.next {
+		return nil
+	}
+	if seq > r.next {
+		if r.MaxWindow > 0 && seq-r.next > r.MaxWindow {
+			return fmt.Errorf("%w: seq %d while waiting for %d", ErrChunkWindow, seq, r.next)
+		}
+		if r.MaxPending > 0 && r.pendingSize+int64(len(chunk)) > r.MaxPending {
+			return fmt.Errorf("%w: more than %d bytes pending", ErrChunkWindow, r.MaxPending)
+		}
+	}
+	r.pending[seq] = chunk
+	r.pendingSize += int64(len(chunk))
+	return nil
+}
+
+func (r *Reassembler) flush() error {
+	for {
+		chunk, ok := r.pending[r.next]
+		if !ok {
+			return nil
+		}
+		delete(r.pending, r.next)
+		r.pendingSize -= int64(len(chunk))
+		if _, err := r.w.Write(chunk); err != nil {
+			return err
+		}
+		r.total.Write(chunk)
+		r.size += int64(len(chunk))
+		r.next++
+	}
+}
+
+func (r *Reassembler) finish() (bool, error) {
+	if r.done {
+		return true, nil
+	}
+	if r.info == nil || r.next < r.info.chunks {
+		return false, nil
+	}
+	if r.size != r.info.size {
+		return false, fmt.Errorf("%w: size %d expected %d", ErrTransferHash, r.size, r.info.size)
+	}
+	if hash := base58.Encode(r.total.Sum(nil)); hash != r.info.hash {
+		return false, fmt.Errorf("%w: %s expected %s", ErrTransferHash, hash, r.info.hash)
+	}
+	r.done = true
+	return true, nil
+}
@@ -0,0 +1,138 @@
+package c5
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ChunkSuite struct {
+	suite.Suite
+}
+
+func (s *ChunkSuite) split(payload []byte, size int) []*EnvelopeT {
+	chunker := NewChunker(ChunkerProps{
+		Src:           "test case",
+		ChunkSize:     size,
+		TimeGenerator: mtimer,
+	})
+	envs := []*EnvelopeT{}
+	err := chunker.Split(bytes.NewReader(payload), func(se *SimpleEnvelope) error {
+		env, err := UnmarshalEnvelopeT([]byte(*se.AsJson()))
+		envs = append(envs, env)
+		return err
+	})
+	assert.NoError(s.T(), err)
+	return envs
+}
+
+func (s *ChunkSuite) payload(n int) []byte {
+	payload := make([]byte, n)
+	rand.New(rand.NewSource(int64(n))).Read(payload)
+	return payload
+}
+
+func (s *ChunkSuite) TestInOrder() {
+	payload := s.payload(1000)
+	envs := s.split(payload, 64)
+	assert.Len(s.T(), envs, 17)
+	assert.Equal(s.T(), TransferKind, envs[16].Data.Kind)
+	out := bytes.Buffer{}
+	r := NewReassembler(&out)
+	for idx, env := range envs {
+		done, err := r.Add(env)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), idx == len(envs)-1, done)
+	}
+	assert.Equal(s.T(), payload, out.Bytes())
+}
+
+func (s *ChunkSuite) TestOutOfOrder() {
+	payload := s.payload(1024)
+	envs := s.split(payload, 100)
+	rand.New(rand.NewSource(4711)).Shuffle(len(envs), func(i, j int) {
+		envs[i], envs[j] = envs[j], envs[i]
+	})
+	out := bytes.Buffer{}
+	r := NewReassembler(&out)
+	for _, env := range envs {
+		_, err := r.Add(env)
+		assert.NoError(s.T(), err)
+		// duplicates are ignored
+		_, err = r.Add(env)
+		assert.NoError(s.T(), err)
+	}
+	assert.True(s.T(), r.Complete())
+	assert.Empty(s.T(), r.Missing())
+	assert.Equal(s.T(), payload, out.Bytes())
+}
+
+func (s *ChunkSuite) TestEmpty() {
+	envs := s.split([]byte{}, 10)
+	assert.Len(s.T(), envs, 1)
+	r := NewReassembler(&bytes.Buffer{})
+	done, err := r.Add(envs[0])
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), done)
+}
+
+func (s *ChunkSuite) TestMissing() {
+	envs := s.split(s.payload(50), 10)
+	r := NewReassembler(&bytes.Buffer{})
+	for _, env := range append(envs[:2:2], envs[3:]...) {
+		done, err := r.Add(env)
+		assert.NoError(s.T(), err)
+		assert.False(s.T(), done)
+	}
+	assert.Equal(s.T(), []int{2}, r.Missing())
+}
+
+func (s *ChunkSuite) TestWindow() {
+	envs := s.split(s.payload(100), 10)
+	r := NewReassembler(&bytes.Buffer{})
+	r.MaxWindow = 3
+	_, err := r.Add(envs[4])
+	assert.True(s.T(), errors.Is(err, ErrChunkWindow))
+	_, err = r.Add(envs[3])
+	assert.NoError(s.T(), err)
+
+	r = NewReassembler(&bytes.Buffer{})
+	r.MaxPending = 25
+	for _, env := range envs[1:3] {
+		_, err = r.Add(env)
+		assert.NoError(s.T(), err)
+	}
+	_, err = r.Add(envs[3])
+	assert.True(s.T(), errors.Is(err, ErrChunkWindow))
+	// the expected chunk releases the held back ones
+	for _, env := range envs {
+		_, err = r.Add(env)
+		assert.NoError(s.T(), err)
+	}
+	assert.True(s.T(), r.Complete())
+}
+
+func (s *ChunkSuite) TestTampered() {
+	envs := s.split(s.payload(50), 10)
+	envs[1].Data.Data["data"] = envs[2].Data.Data["data"]
+	r := NewReassembler(&bytes.Buffer{})
+	_, err := r.Add(envs[0])
+	assert.NoError(s.T(), err)
+	_, err = r.Add(envs[1])
+	assert.True(s.T(), errors.Is(err, ErrChunkInvalid))
+
+	other := s.split(s.payload(50), 10)
+	r = NewReassembler(&bytes.Buffer{})
+	_, err = r.Add(other[0])
+	assert.NoError(s.T(), err)
+	_, err = r.Add(s.split(s.payload(50), 10)[1])
+	assert.True(s.T(), errors.Is(err, ErrChunkInvalid))
+}
+
+func TestChunkSuite(t *testing.T) {
+	suite.Run(t, new(ChunkSuite))
+}
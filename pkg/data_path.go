@@ -0,0 +1,152 @@
+package c5
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Data paths address values in PayloadT1.Data with dot separated
+// attribute names, array elements are addressed by index:
+// "customer.email" or "items.0.ssn".
+func splitDataPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "."), ".")
+}
+
+func dataPathChild(node interface{}, key string) (interface{}, bool) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		val, found := v[key]
+		return val, found
+	case []interface{}:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return nil, false
+		}
+		return v[idx], true
+	}
+	return nil, false
+}
+
+func getDataPath(data interface{}, path []string) (interface{}, bool) {
+	node := data
+	for _, key := range path {
+		var found bool
+		node, found = dataPathChild(node, key)
+		if !found {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+func setDataPath(data interface{}, path []string, val interface{}) error {
+	parent, found := getDataPath(data, path[:len(path)-1])
+	if !found {
+		return fmt.Errorf("path not found:%s", strings.Join(path, "."))
+	}
+	key := path[len(path)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		v[key] = val
+		return nil
+	case []interface{}:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return fmt.Errorf("index out of range:%s", strings.Join(path, "."))
+		}
+		v[idx] = val
+		return nil
+	}
+	return fmt.Errorf("path not addressable:%s", strings.Join(path, "."))
+}
+
+// deleteDataPath removes an attribute; array elements can't be removed
+// without shifting the other ones, so they are set to nil.
+func deleteDataPath(data interface{}, path []string) bool {
+	parent, found := getDataPath(data, path[:len(path)-1])
+	if !found {
+		return false
+	}
+	key := path[len(path)-1]
+	switch v := parent.(type) {
+	case map[string]interface{}:
+		_, found := v[key]
+		delete(v, key)
+		return found
+	case []interface{}:
+		idx, err := strconv.Atoi(key)
+		if err != nil || idx < 0 || idx >= len(v) {
+			return false
+		}
+		v[idx] = nil
+		return true
+	}
+	return false
+}
+
+// copyData deep copies the maps and slices of a data graph, leaves are
+// shared.
+func copyData(data interface{}) interface{} {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			ret[key] = copyData(val)
+		}
+		return ret
+	case []interface{}:
+		ret := make([]interface{}, len(v))
+		for idx, val := range v {
+			ret[idx] = copyData(val)
+		}
+		return ret
+	}
+	return data
+}
+
+func copyPayload(pay PayloadT1) PayloadT1 {
+	data, _ := copyData(pay.Data).(map[string]interface{})
+	return PayloadT1{Kind: pay.Kind, Data: data}
+}
+
+// DataPathWildcard in a path pattern matches every attribute or element.
+const DataPathWildcard = "*"
+
+// expandDataPath returns all existing paths matching pattern.
+func expandDataPath(data interface{}, pattern []string) [][]string {
+	ret := [][]string{}
+	var walk func(node interface{}, idx int, path []string)
+	walk = func(node interface{}, idx int, path []string) {
+		if idx == len(pattern) {
+			ret = append(ret, path)
+			return
+		}
+		key := pattern[idx]
+		if key != DataPathWildcard {
+			child, found := dataPathChild(node, key)
+			if found {
+				walk(child, idx+1, append(path[:len(path):len(path)], key))
+			}
+			return
+		}
+		switch v := node.(type) {
+		case map[string]interface{}:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(v[k], idx+1, append(path[:len(path):len(path)], k))
+			}
+		case []interface{}:
+			for i, val := range v {
+				walk(val, idx+1, append(path[:len(path):len(path)], strconv.Itoa(i)))
+			}
+		}
+	}
+	walk(data, 0, []string{})
+	return ret
+}
@@ -0,0 +1,58 @@
+package c5
+
+import (
+	"fmt"
+)
+
+// DetachedSignature signs the canonical form of se without touching the
+// envelope itself.
+func (s *Signer) DetachedSignature(se *SimpleEnvelope) (*SignatureT, error) {
+	env := se.AsEnvelope()
+	if err := checkHeader(env); err != nil {
+		return nil, err
+	}
+	return s.signature(canonicalJson(env), false, nil)
+}
+
+// SignDetached returns the JSON of a detached signature of se, which is
+// meant to be stored next to the envelope (e.g. as .sig file). Like
+// NewSimpleEnvelope it panics if it can't do its job, use
+// DetachedSignature to get the error instead.
+func (s *Signer) SignDetached(se *SimpleEnvelope) []byte {
+	sig, err := s.DetachedSignature(se)
+	if err != nil {
+		panic(fmt.Sprintf("SignDetached:%v", err))
+	}
+	out, err := sig.Marshal()
+	if err != nil {
+		panic(fmt.Sprintf("SignDetached:%v", err))
+	}
+	return out
+}
+
+// VerifyDetached checks sig against the envelope JSON. The JSON is
+// decoded and canonicalized again, so an envelope written with a
+// different JsonProp indent still verifies. Numbers are decoded with
+// UseNumber, integers above 2^53 keep their digits.
+func (v *Verifier) VerifyDetached(envelopeJSON []byte, sig []byte) (*SignatureResult, error) {
+	env, err := UnmarshalEnvelopeTUseNumber(envelopeJSON)
+	if err != nil {
+		return nil, err
+	}
+	dsig, err := UnmarshalSignatureT(sig)
+	if err != nil {
+		return nil, err
+	}
+	if dsig.Counter {
+		return nil, fmt.Errorf("%w: detached countersignature", ErrSignatureInvalid)
+	}
+	if err := checkHeader(env); err != nil {
+		return nil, err
+	}
+	key, err := v.verifySignature(canonicalJson(env), dsig, nil)
+	res := &SignatureResult{Signature: *dsig, Key: key, Err: err}
+	if err != nil {
+		return res, err
+	}
+	return res, v.Policy.check([]SignatureResult{*res})
+}
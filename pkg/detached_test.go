@@ -0,0 +1,98 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	ogs "github.com/mabels/object-graph-streamer"
+)
+
+type DetachedSuite struct {
+	suite.Suite
+	keyring *Keyring
+	signer  *Signer
+}
+
+func (s *DetachedSuite) SetupTest() {
+	key, err := GenerateKey(AlgEdDSA, "test case")
+	assert.NoError(s.T(), err)
+	s.keyring = NewKeyring(nil)
+	assert.NoError(s.T(), s.keyring.Add(key))
+	s.signer = NewSigner(key)
+	s.signer.TimeGenerator = mtimer
+}
+
+func (s *DetachedSuite) envelope(jsonProp *ogs.JsonProps) *SimpleEnvelope {
+	typ := SampleNameDate{}
+	FromDictSampleNameDate(map[string]interface{}{
+		"name": "object",
+		"date": "2021-05-20",
+	}, &typ)
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: typ.ToDict(),
+		},
+		JsonProp:      jsonProp,
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *DetachedSuite) TestSignVerifyDetached() {
+	se := s.envelope(nil)
+	sig := s.signer.SignDetached(se)
+	res, err := NewVerifier(s.keyring, nil).VerifyDetached([]byte(*se.AsJson()), sig)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), s.signer.Key.ID, res.Key.ID)
+}
+
+func (s *DetachedSuite) TestVerifyIgnoresFormatting() {
+	sig := s.signer.SignDetached(s.envelope(nil))
+	indented := s.envelope(ogs.NewJsonProps(2, ""))
+	_, err := NewVerifier(s.keyring, nil).VerifyDetached([]byte(*indented.AsJson()), sig)
+	assert.NoError(s.T(), err)
+
+	var out bytes.Buffer
+	assert.NoError(s.T(), json.Indent(&out, []byte(*indented.AsJson()), "", "\t"))
+	_, err = NewVerifier(s.keyring, nil).VerifyDetached(out.Bytes(), sig)
+	assert.NoError(s.T(), err)
+}
+
+func (s *DetachedSuite) TestVerifyLargeInteger() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "test",
+			Data: map[string]interface{}{"b": json.Number("9007199254740993")},
+		},
+		TimeGenerator: mtimer,
+	})
+	sig := s.signer.SignDetached(se)
+	_, err := NewVerifier(s.keyring, nil).VerifyDetached([]byte(*se.AsJson()), sig)
+	assert.NoError(s.T(), err)
+}
+
+func (s *DetachedSuite) TestVerifyDetectsChanges() {
+	se := s.envelope(nil)
+	sig := s.signer.SignDetached(se)
+	changed := bytes.Replace([]byte(*se.AsJson()), []byte(`"object"`), []byte(`"subject"`), 1)
+	_, err := NewVerifier(s.keyring, nil).VerifyDetached(changed, sig)
+	assert.True(s.T(), errors.Is(err, ErrSignatureInvalid))
+}
+
+func (s *DetachedSuite) TestSignDetachedPanicsWithoutPrivateKey() {
+	signer := NewSigner(s.signer.Key.PublicOnly())
+	assert.Panics(s.T(), func() { signer.SignDetached(s.envelope(nil)) })
+	_, err := signer.DetachedSignature(s.envelope(nil))
+	assert.True(s.T(), errors.Is(err, ErrNoSigningKey))
+}
+
+func TestDetachedSuite(t *testing.T) {
+	suite.Run(t, new(DetachedSuite))
+}
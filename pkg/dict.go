@@ -0,0 +1,129 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// The As* conversions are used by the FromDict functions which c5gen
+// generates. They accept every type a dict may hold after json.Unmarshal,
+// a json.Decoder with UseNumber or a ToDict and return an error instead of
+// panicking on anything else.
+
+var (
+	ErrDictMissing = errors.New("missing field")
+	ErrDictType    = errors.New("unexpected type")
+	ErrDictEnum    = errors.New("enum not found")
+)
+
+func AsString(v interface{}) (string, error) {
+	str, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %T is no string", ErrDictType, v)
+	}
+	return str, nil
+}
+
+func AsBool(v interface{}) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%w: %T is no bool", ErrDictType, v)
+	}
+	return b, nil
+}
+
+func AsFloat64(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case json.Number:
+		f, err := n.Float64()
+		if err != nil {
+			return 0, fmt.Errorf("%w: %s", ErrDictType, err)
+		}
+		return f, nil
+	}
+	valOf := reflect.ValueOf(v)
+	switch valOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(valOf.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(valOf.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return valOf.Float(), nil
+	}
+	return 0, fmt.Errorf("%w: %T is no number", ErrDictType, v)
+}
+
+// AsInt64 accepts integral numbers of every type.
+func AsInt64(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case json.Number:
+		i, err := strconv.ParseInt(string(n), 10, 64)
+		if err == nil {
+			return i, nil
+		}
+	}
+	valOf := reflect.ValueOf(v)
+	switch valOf.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return valOf.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if valOf.Uint() <= math.MaxInt64 {
+			return int64(valOf.Uint()), nil
+		}
+	}
+	f, err := AsFloat64(v)
+	if err != nil {
+		return 0, err
+	}
+	if f != math.Trunc(f) || math.Abs(f) > 1<<53 {
+		return 0, fmt.Errorf("%w: %v is no integer", ErrDictType, v)
+	}
+	return int64(f), nil
+}
+
+// AsObject returns maps with string keys as map[string]interface{}.
+func AsObject(v interface{}) (map[string]interface{}, error) {
+	if obj, ok := v.(map[string]interface{}); ok {
+		return obj, nil
+	}
+	valOf := reflect.ValueOf(v)
+	if valOf.Kind() != reflect.Map || valOf.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("%w: %T is no object", ErrDictType, v)
+	}
+	ret := make(map[string]interface{}, valOf.Len())
+	iter := valOf.MapRange()
+	for iter.Next() {
+		ret[iter.Key().String()] = iter.Value().Interface()
+	}
+	return ret, nil
+}
+
+// AsArray returns slices and arrays of any type as []interface{}.
+func AsArray(v interface{}) ([]interface{}, error) {
+	if arr, ok := v.([]interface{}); ok {
+		return arr, nil
+	}
+	valOf := reflect.ValueOf(v)
+	if valOf.Kind() != reflect.Slice && valOf.Kind() != reflect.Array {
+		return nil, fmt.Errorf("%w: %T is no array", ErrDictType, v)
+	}
+	ret := make([]interface{}, valOf.Len())
+	for idx := range ret {
+		ret[idx] = valOf.Index(idx).Interface()
+	}
+	return ret, nil
+}
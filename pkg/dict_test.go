@@ -0,0 +1,78 @@
+package c5
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DictSuite struct {
+	suite.Suite
+}
+
+func (s *DictSuite) envelope() map[string]interface{} {
+	return map[string]interface{}{
+		"v":    "A",
+		"id":   "id",
+		"src":  "src",
+		"dst":  []string{"a"},
+		"t":    json.Number("4711"),
+		"ttl":  uint8(10),
+		"data": map[string]interface{}{"kind": "test", "data": map[string]string{"x": "y"}},
+	}
+}
+
+func (s *DictSuite) TestFromDict() {
+	env := EnvelopeT{}
+	assert.NoError(s.T(), FromDictEnvelopeT(s.envelope(), &env))
+	assert.Equal(s.T(), EnvelopeT{
+		V:    V_A,
+		ID:   "id",
+		Src:  "src",
+		Dst:  []string{"a"},
+		T:    4711,
+		TTL:  10,
+		Data: PayloadT1{Kind: "test", Data: map[string]interface{}{"x": "y"}},
+	}, env)
+}
+
+func (s *DictSuite) TestErrorsInsteadOfPanics() {
+	for key, val := range map[string]interface{}{
+		"v":    "B",
+		"id":   nil,
+		"dst":  "a",
+		"t":    "now",
+		"data": map[string]interface{}{"kind": 4},
+	} {
+		dict := s.envelope()
+		dict[key] = val
+		env := EnvelopeT{}
+		err := FromDictEnvelopeT(dict, &env)
+		assert.Error(s.T(), err, key)
+		assert.True(s.T(), errors.Is(err, ErrDictMissing) || errors.Is(err, ErrDictType) || errors.Is(err, ErrDictEnum), key)
+	}
+	dict := s.envelope()
+	dict["signatures"] = []interface{}{map[string]interface{}{"alg": "x"}}
+	err := FromDictEnvelopeT(dict, &EnvelopeT{})
+	assert.True(s.T(), errors.Is(err, ErrDictMissing))
+	assert.Contains(s.T(), err.Error(), "EnvelopeT.signatures[0]")
+}
+
+func (s *DictSuite) TestAsInt64() {
+	for _, v := range []interface{}{7, int64(7), uint16(7), 7.0, json.Number("7"), json.Number("7.0")} {
+		i, err := AsInt64(v)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), int64(7), i)
+	}
+	for _, v := range []interface{}{7.5, json.Number("1e300"), "7", uint64(1 << 63)} {
+		_, err := AsInt64(v)
+		assert.True(s.T(), errors.Is(err, ErrDictType), "%v", v)
+	}
+}
+
+func TestDictSuite(t *testing.T) {
+	suite.Run(t, new(DictSuite))
+}
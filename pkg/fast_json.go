@@ -0,0 +1,358 @@
+package c5
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// The fast path renders the same bytes and hash as ogs.JsonCollector and
+// ogs.HashCollector but writes into pooled buffers and keeps no paths.
+// Only the common JSON types are formatted inline, numbers are canonical
+// like canonicalizeData and everything else takes the same json.Marshal
+// and fmt route as the object graph streamer.
+
+// jsonState mirrors the state of an ogs.JsonCollector.
+type jsonState struct {
+	nextLine string
+	commas   []bool
+	elements []int
+	attr     string
+	hasAttr  bool
+}
+
+func (st *jsonState) reset(nextLine string) {
+	st.nextLine = nextLine
+	st.commas = append(st.commas[:0], false)
+	st.elements = append(st.elements[:0], 0)
+	st.hasAttr = false
+}
+
+type encoder struct {
+	buf     []byte
+	scratch []byte
+	hash    hash.Hash
+	hashing bool
+	indent  int
+	env     jsonState
+	data    jsonState
+	keys    [][]string
+	depth   int
+	mapper  typeMapper
+}
+
+// maxPooledBuffer caps the buffers kept in encoderPool, one large envelope
+// shouldn't pin its memory for the lifetime of the pool.
+const maxPooledBuffer = 64 * 1024
+
+var encoderPool = sync.Pool{
+	New: func() interface{} {
+		return &encoder{hash: sha256.New()}
+	},
+}
+
+func getEncoder() *encoder {
+	return encoderPool.Get().(*encoder)
+}
+
+func (e *encoder) reusable() bool {
+	return cap(e.buf) <= maxPooledBuffer && cap(e.scratch) <= maxPooledBuffer
+}
+
+func putEncoder(e *encoder) {
+	if e.reusable() {
+		encoderPool.Put(e)
+	}
+}
+
+func (e *encoder) prefix(st *jsonState) {
+	if st.commas[len(st.commas)-1] {
+		e.buf = append(e.buf, ',')
+	}
+	if st.elements[len(st.elements)-1] > 0 {
+		e.buf = append(e.buf, st.nextLine...)
+		for i := 0; i < (len(st.commas)-1)*e.indent; i++ {
+			e.buf = append(e.buf, ' ')
+		}
+	}
+	if st.hasAttr {
+		e.buf = appendJsonString(e.buf, st.attr)
+		e.buf = append(e.buf, ':')
+		if e.indent > 0 {
+			e.buf = append(e.buf, ' ')
+		}
+		st.hasAttr = false
+	}
+}
+
+func (e *encoder) start(st *jsonState, c byte) {
+	e.prefix(st)
+	e.buf = append(e.buf, c)
+	st.commas[len(st.commas)-1] = true
+	st.commas = append(st.commas, false)
+	st.elements = append(st.elements, 0)
+}
+
+func (e *encoder) end(st *jsonState, c byte) {
+	st.commas = st.commas[:len(st.commas)-1]
+	if st.elements[len(st.elements)-1] > 0 {
+		e.buf = append(e.buf, st.nextLine...)
+		for i := 0; i < (len(st.commas)-1)*e.indent; i++ {
+			e.buf = append(e.buf, ' ')
+		}
+	}
+	e.buf = append(e.buf, c)
+	st.elements = st.elements[:len(st.elements)-1]
+}
+
+func (e *encoder) attribute(st *jsonState, key string) {
+	st.elements[len(st.elements)-1]++
+	st.attr = key
+	st.hasAttr = true
+	if e.hashing {
+		e.scratch = append(e.scratch[:0], key...)
+		e.hash.Write(e.scratch)
+	}
+}
+
+func (e *encoder) value(st *jsonState, v interface{}) {
+	st.elements[len(st.elements)-1]++
+	e.prefix(st)
+	e.buf = appendJsonValue(e.buf, v)
+	st.commas[len(st.commas)-1] = true
+	if e.hashing {
+		e.scratch = appendHashValue(e.scratch[:0], v)
+		e.hash.Write(e.scratch)
+	}
+}
+
+func (e *encoder) sortedKeys(m map[string]interface{}) []string {
+	if e.depth == len(e.keys) {
+		e.keys = append(e.keys, nil)
+	}
+	keys := e.keys[e.depth][:0]
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	e.keys[e.depth] = keys
+	return keys
+}
+
+// walk follows the rules of ogs.ObjectGraphStreamer on the mapped data.
+func (e *encoder) walk(st *jsonState, v interface{}) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		e.start(st, '{')
+		keys := e.sortedKeys(t)
+		e.depth++
+		for _, key := range keys {
+			e.attribute(st, key)
+			e.walk(st, t[key])
+		}
+		e.depth--
+		e.end(st, '}')
+	case []interface{}:
+		e.start(st, '[')
+		for _, item := range t {
+			e.walk(st, item)
+		}
+		e.end(st, ']')
+	case nil, string, float64, int, int64, bool, json.Number:
+		e.value(st, v)
+	default:
+		switch m := e.mapper.data(v).(type) {
+		case map[string]interface{}, []interface{}, string, bool, json.Number, nil:
+			e.walk(st, m)
+		default:
+			e.value(st, m)
+		}
+	}
+}
+
+func jsonSafeASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < 0x20 || c >= 0x7f || c == '"' || c == '\\' || c == '<' || c == '>' || c == '&' {
+			return false
+		}
+	}
+	return true
+}
+
+func appendMarshal(dst []byte, v interface{}) []byte {
+	out, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return append(dst, out...)
+}
+
+func appendJsonString(dst []byte, s string) []byte {
+	if !jsonSafeASCII(s) {
+		return appendMarshal(dst, s)
+	}
+	dst = append(dst, '"')
+	dst = append(dst, s...)
+	return append(dst, '"')
+}
+
+// appendJsonValue is json.Marshal of v.
+func appendJsonValue(dst []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return appendJsonString(dst, t)
+	case bool:
+		return strconv.AppendBool(dst, t)
+	case int:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case int64:
+		return strconv.AppendInt(dst, t, 10)
+	case float64:
+		if ret, ok := appendCanonicalFloat(dst, t, 64); ok {
+			return ret
+		}
+	}
+	if num, ok := CanonicalNumber(v); ok {
+		return append(dst, num...)
+	}
+	return appendMarshal(dst, v)
+}
+
+// appendHashValue is what hashCollector hashes for v.
+func appendHashValue(dst []byte, v interface{}) []byte {
+	switch t := v.(type) {
+	case nil:
+		return append(dst, "null"...)
+	case string:
+		return append(dst, t...)
+	case bool:
+		return strconv.AppendBool(dst, t)
+	case int:
+		return strconv.AppendInt(dst, int64(t), 10)
+	case int64:
+		return strconv.AppendInt(dst, t, 10)
+	case float64:
+		if ret, ok := appendCanonicalFloat(dst, t, 64); ok {
+			return ret
+		}
+	}
+	if num, ok := CanonicalNumber(v); ok {
+		return append(dst, num...)
+	}
+	return append(dst, fmt.Sprintf("%v", v)...)
+}
+
+func (e *encoder) reset(indent int, newline string) {
+	e.buf = e.buf[:0]
+	e.indent = indent
+	e.depth = 0
+	e.hashing = false
+	nextLine := ""
+	dataNextLine := ""
+	if indent > 0 {
+		nextLine = newline
+		dataNextLine = "\n"
+		for i := 0; i < 2*indent; i++ {
+			dataNextLine += " "
+		}
+	}
+	e.env.reset(nextLine)
+	e.data.reset(dataNextLine)
+}
+
+func (e *encoder) encode(s *SimpleEnvelope) {
+	props := s.simpleEnvelopeProps
+	indent := 0
+	newline := ""
+	if props.JsonProp != nil {
+		indent = props.JsonProp.Indent
+		newline = props.JsonProp.Newline
+		if newline == "" {
+			newline = "\n"
+		}
+	}
+	e.reset(indent, newline)
+	e.mapper.serializers = props.Serializers
+	env := &e.env
+	e.start(env, '{')
+	e.attribute(env, "data")
+	e.start(env, '{')
+	e.attribute(env, "data")
+	// the data is rendered by its own collector like in lazy
+	env.elements[len(env.elements)-1]++
+	e.prefix(env)
+	env.commas[len(env.commas)-1] = true
+	var hash *string
+	if props.ID == "" {
+		e.hash.Reset()
+		e.hashing = true
+	}
+	e.walk(&e.data, props.Data.Data)
+	if e.hashing {
+		e.hashing = false
+		digest := base58.Encode(e.hash.Sum(e.scratch[:0]))
+		hash = &digest
+	}
+	e.attribute(env, "kind")
+	e.value(env, props.Data.Kind)
+	e.end(env, '}')
+	e.attribute(env, "dst")
+	e.start(env, '[')
+	for _, dst := range props.Dst {
+		e.value(env, dst)
+	}
+	e.end(env, ']')
+	e.attribute(env, "id")
+	e.value(env, s.generateID(hash))
+	e.attribute(env, "src")
+	e.value(env, props.Src)
+	e.attribute(env, "t")
+	e.value(env, float64(props.T))
+	ttl := props.TTL
+	if ttl == 0 && !props.receivedTTL {
+		ttl = 10
+	}
+	e.attribute(env, "ttl")
+	e.value(env, float64(ttl))
+	e.attribute(env, "v")
+	e.value(env, ToV(V_A))
+	e.end(env, '}')
+}
+
+// AppendJson appends the same bytes as AsJson to dst. It renders the
+// envelope on every call without keeping state in s, which makes it the
+// high-throughput path for envelopes written once.
+func (s *SimpleEnvelope) AppendJson(dst []byte) []byte {
+	if s.envJsonString != nil {
+		return append(dst, *s.envJsonString...)
+	}
+	e := getEncoder()
+	e.encode(s)
+	dst = append(dst, e.buf...)
+	putEncoder(e)
+	return dst
+}
+
+// EncodeJson writes the same bytes as AsJson to w through a pooled
+// buffer.
+func (s *SimpleEnvelope) EncodeJson(w io.Writer) error {
+	if s.envJsonString != nil {
+		_, err := io.WriteString(w, *s.envJsonString)
+		return err
+	}
+	e := getEncoder()
+	defer putEncoder(e)
+	e.encode(s)
+	_, err := w.Write(e.buf)
+	return err
+}
@@ -0,0 +1,216 @@
+package c5
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	ogs "github.com/mabels/object-graph-streamer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FastJsonSuite struct {
+	suite.Suite
+}
+
+type fastJsonSample struct {
+	Name  string   `json:"name"`
+	Tags  []string `json:"tags"`
+	Count int
+}
+
+func randomValue(rnd *rand.Rand, depth int) interface{} {
+	switch n := rnd.Intn(12); {
+	case n == 0 && depth < 4:
+		m := map[string]interface{}{}
+		for i := rnd.Intn(5); i > 0; i-- {
+			m[fmt.Sprintf("k%d", rnd.Intn(100))] = randomValue(rnd, depth+1)
+		}
+		return m
+	case n == 1 && depth < 4:
+		a := []interface{}{}
+		for i := rnd.Intn(5); i > 0; i-- {
+			a = append(a, randomValue(rnd, depth+1))
+		}
+		return a
+	case n == 2:
+		return rnd.NormFloat64() * 1e6
+	case n == 3:
+		return float64(rnd.Int63n(1 << 53))
+	case n == 4:
+		return []float64{1e-7, 1e21, 0.1, -0}[rnd.Intn(4)]
+	case n == 5:
+		return rnd.Intn(1000) - 500
+	case n == 6:
+		return []string{"plain", "<html>&", "quote\"back\\slash", "tab\tnew\nline", "ümlaut €", " ", "\x01\b\f"}[rnd.Intn(7)]
+	case n == 7:
+		return rnd.Intn(2) == 0
+	case n == 8:
+		return nil
+	case n == 9:
+		return time.Date(2021, 6, 20, 10, 0, 0, rnd.Intn(1000)*1e6, time.UTC)
+	case n == 10:
+		return fastJsonSample{Name: "sample", Tags: []string{"a", "b"}, Count: rnd.Intn(10)}
+	}
+	return int64(rnd.Int63())
+}
+
+func (s *FastJsonSuite) props(data map[string]interface{}, id string, jsonProp *ogs.JsonProps) *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		ID:  id,
+		Src: "test case",
+		Dst: []string{"a", "<b>"},
+		Data: PayloadT1{
+			Kind: "fast",
+			Data: data,
+		},
+		JsonProp:      jsonProp,
+		TimeGenerator: mtimer,
+	}
+}
+
+func (s *FastJsonSuite) TestSameAsAsJson() {
+	rnd := rand.New(rand.NewSource(4711))
+	jsonProps := []*ogs.JsonProps{nil, ogs.NewJsonProps(2, ""), ogs.NewJsonProps(4, "\r\n")}
+	for i := 0; i < 500; i++ {
+		data := map[string]interface{}{}
+		for j := rnd.Intn(6); j > 0; j-- {
+			data[fmt.Sprintf("a%d", j)] = randomValue(rnd, 0)
+		}
+		jsonProp := jsonProps[i%len(jsonProps)]
+		id := ""
+		if i%5 == 0 {
+			id = "given"
+		}
+		expected := *NewSimpleEnvelope(s.props(data, id, jsonProp)).AsJson()
+		fast := NewSimpleEnvelope(s.props(data, id, jsonProp))
+		if !assert.Equal(s.T(), expected, string(fast.AppendJson(nil)), "run %d", i) {
+			return
+		}
+		out := bytes.Buffer{}
+		assert.NoError(s.T(), fast.EncodeJson(&out))
+		assert.Equal(s.T(), expected, out.String())
+	}
+}
+
+func (s *FastJsonSuite) TestNoDst() {
+	props := s.props(map[string]interface{}{}, "", ogs.NewJsonProps(2, ""))
+	props.Dst = nil
+	assert.Equal(s.T(), *NewSimpleEnvelope(props).AsJson(), string(NewSimpleEnvelope(props).AppendJson(nil)))
+}
+
+func (s *FastJsonSuite) TestPoolCap() {
+	big := map[string]interface{}{"blob": strings.Repeat("x", 2*maxPooledBuffer)}
+	e := getEncoder()
+	e.encode(NewSimpleEnvelope(s.props(big, "", nil)))
+	assert.False(s.T(), e.reusable())
+	putEncoder(e)
+
+	e = getEncoder()
+	e.encode(NewSimpleEnvelope(s.props(map[string]interface{}{"y": 4}, "", nil)))
+	assert.True(s.T(), e.reusable())
+	putEncoder(e)
+}
+
+func TestFastJsonSuite(t *testing.T) {
+	suite.Run(t, new(FastJsonSuite))
+}
+
+func benchmarkData() map[string]interface{} {
+	items := []interface{}{}
+	for i := 0; i < 20; i++ {
+		items = append(items, map[string]interface{}{
+			"sku":   fmt.Sprintf("sku-%d", i),
+			"price": float64(i) * 1.25,
+			"qty":   i,
+			"tags":  []interface{}{"a", "b", "c"},
+		})
+	}
+	return map[string]interface{}{
+		"customer": map[string]interface{}{"name": "Meno Abels", "email": "meno@example.com"},
+		"items":    items,
+		"total":    1234.5,
+		"paid":     true,
+	}
+}
+
+func benchmarkProps(indent int) *SimpleEnvelopeProps {
+	var jsonProp *ogs.JsonProps
+	if indent > 0 {
+		jsonProp = ogs.NewJsonProps(indent, "")
+	}
+	return &SimpleEnvelopeProps{
+		Src:           "bench",
+		Dst:           []string{"gateway"},
+		Data:          PayloadT1{Kind: "order", Data: benchmarkData()},
+		JsonProp:      jsonProp,
+		TimeGenerator: mtimer,
+	}
+}
+
+func BenchmarkAsJson(b *testing.B) {
+	props := benchmarkProps(0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := *props
+		NewSimpleEnvelope(&p).AsJson()
+	}
+}
+
+func BenchmarkAsJsonIndent(b *testing.B) {
+	props := benchmarkProps(2)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := *props
+		NewSimpleEnvelope(&p).AsJson()
+	}
+}
+
+func BenchmarkAppendJson(b *testing.B) {
+	props := benchmarkProps(0)
+	buf := make([]byte, 0, 4096)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := *props
+		buf = NewSimpleEnvelope(&p).AppendJson(buf[:0])
+	}
+}
+
+func BenchmarkAppendJsonIndent(b *testing.B) {
+	props := benchmarkProps(2)
+	buf := make([]byte, 0, 8192)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := *props
+		buf = NewSimpleEnvelope(&p).AppendJson(buf[:0])
+	}
+}
+
+func BenchmarkEncodeJson(b *testing.B) {
+	props := benchmarkProps(0)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		p := *props
+		if err := NewSimpleEnvelope(&p).EncodeJson(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncodeJsonParallel(b *testing.B) {
+	props := benchmarkProps(0)
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			p := *props
+			if err := NewSimpleEnvelope(&p).EncodeJson(io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
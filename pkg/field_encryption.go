@@ -0,0 +1,193 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// EncryptedFieldKey marks a value which got replaced by its ciphertext:
+	// {"_enc":{"alg":"A256GCM","kid":"...","iv":"...","ct":"..."}}
+	EncryptedFieldKey = "_enc"
+	AlgA128GCM        = "A128GCM"
+	AlgA192GCM        = "A192GCM"
+	AlgA256GCM        = "A256GCM"
+)
+
+var (
+	ErrFieldNotFound     = errors.New("field not found")
+	ErrFieldDecryption   = errors.New("field decryption failed")
+	ErrFieldAlreadyCrypt = errors.New("field is already encrypted")
+)
+
+// FieldCipher encrypts selected values of PayloadT1.Data with AES-GCM.
+// The additional data of every value is the envelope ID and the path, so
+// ciphertexts can't be moved between fields or envelopes.
+type FieldCipher struct {
+	Kid  string
+	alg  string
+	aead cipher.AEAD
+}
+
+func NewFieldCipher(kid string, key []byte) (*FieldCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FieldCipher{
+		Kid:  kid,
+		alg:  fmt.Sprintf("A%dGCM", len(key)*8),
+		aead: aead,
+	}, nil
+}
+
+func fieldAAD(id string, path string) []byte {
+	return []byte(id + "\n" + path)
+}
+
+func encryptedField(val interface{}) (map[string]interface{}, bool) {
+	obj, ok := val.(map[string]interface{})
+	if !ok || len(obj) != 1 {
+		return nil, false
+	}
+	enc, ok := obj[EncryptedFieldKey].(map[string]interface{})
+	return enc, ok
+}
+
+func (fc *FieldCipher) encrypt(id string, path string, val interface{}) (map[string]interface{}, error) {
+	plain, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, fc.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	ct := fc.aead.Seal(nil, iv, plain, fieldAAD(id, path))
+	return map[string]interface{}{
+		EncryptedFieldKey: map[string]interface{}{
+			"alg": fc.alg,
+			"kid": fc.Kid,
+			"iv":  b64url.EncodeToString(iv),
+			"ct":  b64url.EncodeToString(ct),
+		},
+	}, nil
+}
+
+func (fc *FieldCipher) decrypt(id string, path string, enc map[string]interface{}) (interface{}, error) {
+	if enc["alg"] != fc.alg {
+		return nil, fmt.Errorf("%w: %s unknown alg %v", ErrFieldDecryption, path, enc["alg"])
+	}
+	ivStr, _ := enc["iv"].(string)
+	ctStr, _ := enc["ct"].(string)
+	iv, err := b64url.DecodeString(ivStr)
+	if err != nil || len(iv) != fc.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %s invalid iv", ErrFieldDecryption, path)
+	}
+	ct, err := b64url.DecodeString(ctStr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s invalid ct", ErrFieldDecryption, path)
+	}
+	plain, err := fc.aead.Open(nil, iv, ct, fieldAAD(id, path))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s %v", ErrFieldDecryption, path, err)
+	}
+	// json.Number keeps integers above 2^53, the id covers their digits
+	var val interface{}
+	dec := json.NewDecoder(bytes.NewReader(plain))
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// EncryptFields returns a copy of se where the values at paths are
+// replaced by their ciphertext. The ID of the copy is the one of se, so
+// the hash it's made of covers the plaintext.
+func (fc *FieldCipher) EncryptFields(se *SimpleEnvelope, paths ...string) (*SimpleEnvelope, error) {
+	env := se.AsEnvelope()
+	pay := copyPayload(env.Data)
+	for _, path := range paths {
+		dpath := splitDataPath(path)
+		val, found := getDataPath(pay.Data, dpath)
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotFound, path)
+		}
+		if _, isEnc := encryptedField(val); isEnc {
+			return nil, fmt.Errorf("%w: %s", ErrFieldAlreadyCrypt, path)
+		}
+		enc, err := fc.encrypt(env.ID, strings.Join(dpath, "."), val)
+		if err != nil {
+			return nil, err
+		}
+		if err := setDataPath(pay.Data, dpath, enc); err != nil {
+			return nil, err
+		}
+	}
+	ret := *env
+	ret.Data = pay
+	return NewSimpleEnvelopeFromEnvelopeT(&ret, se.simpleEnvelopeProps.JsonProp), nil
+}
+
+// EncryptedFields lists the paths of all encrypted values of data.
+func EncryptedFields(data map[string]interface{}) []string {
+	ret := []string{}
+	var walk func(node interface{}, path []string)
+	walk = func(node interface{}, path []string) {
+		if _, isEnc := encryptedField(node); isEnc {
+			ret = append(ret, strings.Join(path, "."))
+			return
+		}
+		switch v := node.(type) {
+		case map[string]interface{}:
+			for key, val := range v {
+				walk(val, append(path[:len(path):len(path)], key))
+			}
+		case []interface{}:
+			for idx, val := range v {
+				walk(val, append(path[:len(path):len(path)], strconv.Itoa(idx)))
+			}
+		}
+	}
+	walk(data, []string{})
+	sort.Strings(ret)
+	return ret
+}
+
+// DecryptFields returns env with every value encrypted under this kid
+// decrypted. Values of other kids stay encrypted, once all are decrypted
+// VerifyID holds again.
+func (fc *FieldCipher) DecryptFields(env *EnvelopeT) (*EnvelopeT, error) {
+	pay := copyPayload(env.Data)
+	for _, path := range EncryptedFields(pay.Data) {
+		dpath := splitDataPath(path)
+		val, _ := getDataPath(pay.Data, dpath)
+		enc, _ := encryptedField(val)
+		if enc["kid"] != fc.Kid {
+			continue
+		}
+		plain, err := fc.decrypt(env.ID, path, enc)
+		if err != nil {
+			return nil, err
+		}
+		if err := setDataPath(pay.Data, dpath, plain); err != nil {
+			return nil, err
+		}
+	}
+	ret := *env
+	ret.Data = pay
+	return &ret, nil
+}
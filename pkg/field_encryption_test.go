@@ -0,0 +1,121 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type FieldEncryptionSuite struct {
+	suite.Suite
+	cipher *FieldCipher
+}
+
+func (s *FieldEncryptionSuite) SetupTest() {
+	var err error
+	s.cipher, err = NewFieldCipher("k1", bytes.Repeat([]byte{7}, 32))
+	assert.NoError(s.T(), err)
+}
+
+func (s *FieldEncryptionSuite) envelope() *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "customer",
+			Data: map[string]interface{}{
+				"customer": map[string]interface{}{
+					"name":  "Meno",
+					"email": "meno@example.com",
+					"ssn":   "123-45-6789",
+				},
+				"items": []interface{}{
+					map[string]interface{}{"sku": "a", "price": 4.5},
+				},
+			},
+		},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *FieldEncryptionSuite) TestEncryptDecryptKeepsID() {
+	plain := s.envelope()
+	enc, err := s.cipher.EncryptFields(plain, "customer.email", "customer.ssn", "items.0.price")
+	assert.NoError(s.T(), err)
+	encEnv := enc.AsEnvelope()
+	assert.Equal(s.T(), plain.AsEnvelope().ID, encEnv.ID)
+	assert.NotContains(s.T(), *enc.AsJson(), "meno@example.com")
+	assert.Contains(s.T(), *enc.AsJson(), `"name":"Meno"`)
+	assert.Equal(s.T(), []string{"customer.email", "customer.ssn", "items.0.price"}, EncryptedFields(encEnv.Data.Data))
+	assert.True(s.T(), errors.Is(VerifyID(encEnv), ErrIDMismatch))
+
+	// the original is untouched
+	assert.Equal(s.T(), "meno@example.com", plain.AsEnvelope().Data.Data["customer"].(map[string]interface{})["email"])
+
+	ref, err := UnmarshalEnvelopeT([]byte(*enc.AsJson()))
+	assert.NoError(s.T(), err)
+	dec, err := s.cipher.DecryptFields(ref)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), VerifyID(dec))
+	assert.Equal(s.T(), *plain.AsJson(), *NewSimpleEnvelopeFromEnvelopeT(dec, nil).AsJson())
+}
+
+func (s *FieldEncryptionSuite) TestLargeInteger() {
+	plain := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "account", Data: map[string]interface{}{"balance": int64(9007199254740993)}},
+		TimeGenerator: mtimer,
+	})
+	enc, err := s.cipher.EncryptFields(plain, "balance")
+	assert.NoError(s.T(), err)
+	ref, err := UnmarshalEnvelopeTUseNumber([]byte(*enc.AsJson()))
+	assert.NoError(s.T(), err)
+	dec, err := s.cipher.DecryptFields(ref)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), json.Number("9007199254740993"), dec.Data.Data["balance"])
+	assert.NoError(s.T(), VerifyID(dec))
+}
+
+func (s *FieldEncryptionSuite) TestOtherKidStaysEncrypted() {
+	other, err := NewFieldCipher("k2", bytes.Repeat([]byte{8}, 16))
+	assert.NoError(s.T(), err)
+	enc, err := s.cipher.EncryptFields(s.envelope(), "customer.email")
+	assert.NoError(s.T(), err)
+	enc, err = other.EncryptFields(enc, "customer.ssn")
+	assert.NoError(s.T(), err)
+
+	dec, err := s.cipher.DecryptFields(enc.AsEnvelope())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), []string{"customer.ssn"}, EncryptedFields(dec.Data.Data))
+	dec, err = other.DecryptFields(dec)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), VerifyID(dec))
+}
+
+func (s *FieldEncryptionSuite) TestCiphertextIsBoundToPath() {
+	enc, err := s.cipher.EncryptFields(s.envelope(), "customer.email", "customer.ssn")
+	assert.NoError(s.T(), err)
+	env := enc.AsEnvelope()
+	customer := env.Data.Data["customer"].(map[string]interface{})
+	customer["email"], customer["ssn"] = customer["ssn"], customer["email"]
+	_, err = s.cipher.DecryptFields(env)
+	assert.True(s.T(), errors.Is(err, ErrFieldDecryption))
+}
+
+func (s *FieldEncryptionSuite) TestErrors() {
+	_, err := s.cipher.EncryptFields(s.envelope(), "customer.phone")
+	assert.True(s.T(), errors.Is(err, ErrFieldNotFound))
+	enc, err := s.cipher.EncryptFields(s.envelope(), "customer")
+	assert.NoError(s.T(), err)
+	_, err = s.cipher.EncryptFields(enc, "customer")
+	assert.True(s.T(), errors.Is(err, ErrFieldAlreadyCrypt))
+	_, err = NewFieldCipher("k", []byte("short"))
+	assert.Error(s.T(), err)
+}
+
+func TestFieldEncryptionSuite(t *testing.T) {
+	suite.Run(t, new(FieldEncryptionSuite))
+}
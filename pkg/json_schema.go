@@ -0,0 +1,209 @@
+package c5
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// JSONSchemaDraft is the dialect of the document of JSONSchema.
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchemaEnum is implemented by string types with a fixed set of
+// values, c5gen generates it for enums.
+type JSONSchemaEnum interface {
+	EnumValues() []string
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	jsonNumberType = reflect.TypeOf(json.Number(""))
+	enumType       = reflect.TypeOf((*JSONSchemaEnum)(nil)).Elem()
+)
+
+// schemaBuilder derives schemas from Go types like encoding/json marshals
+// them, named structs and enums become definitions.
+type schemaBuilder struct {
+	definitions map[string]interface{}
+	names       map[reflect.Type]string
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		definitions: map[string]interface{}{},
+		names:       map[reflect.Type]string{},
+	}
+}
+
+// ref adds the definition of t once and refers to it, types of the same
+// name from different packages are prefixed with their package.
+func (b *schemaBuilder) ref(t reflect.Type, define func() map[string]interface{}) map[string]interface{} {
+	name, found := b.names[t]
+	if !found {
+		name = t.Name()
+		if _, taken := b.definitions[name]; taken {
+			name = path.Base(t.PkgPath()) + "." + name
+		}
+		b.names[t] = name
+		// placeholder for recursive types
+		b.definitions[name] = map[string]interface{}{}
+		b.definitions[name] = define()
+	}
+	return map[string]interface{}{"$ref": "#/definitions/" + name}
+}
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case jsonNumberType:
+		return map[string]interface{}{"type": "number"}
+	}
+	if t.Kind() == reflect.String && t.Implements(enumType) {
+		return b.ref(t, func() map[string]interface{} {
+			values := reflect.Zero(t).Interface().(JSONSchemaEnum).EnumValues()
+			return map[string]interface{}{"type": "string", "enum": values}
+		})
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		ret := map[string]interface{}{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			ret["additionalProperties"] = b.schema(t.Elem())
+		}
+		return ret
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		return b.ref(t, func() map[string]interface{} {
+			return b.object(t)
+		})
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	b.fields(t, properties, &required)
+	sort.Strings(required)
+	ret := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		ret["required"] = required
+	}
+	return ret
+}
+
+// fields follows the field rules of canonicalizeStruct.
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	embedded := []reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		fl := t.Field(i)
+		field, ok := parseJsonTag(fl)
+		if !ok {
+			continue
+		}
+		if fl.Anonymous {
+			_, tagged := fl.Tag.Lookup("json")
+			ft := fl.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if !tagged && ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if fl.PkgPath != "" {
+			continue
+		}
+		prop := b.schema(fl.Type)
+		if description, found := fl.Tag.Lookup("description"); found {
+			if _, isRef := prop["$ref"]; isRef {
+				// siblings of $ref are ignored in draft-07
+				prop = map[string]interface{}{"allOf": []interface{}{prop}}
+			}
+			prop["description"] = description
+		}
+		properties[field.name] = prop
+		if !field.omitEmpty {
+			*required = append(*required, field.name)
+		}
+	}
+	for _, et := range embedded {
+		inner := map[string]interface{}{}
+		innerRequired := []string{}
+		b.fields(et, inner, &innerRequired)
+		for name, prop := range inner {
+			if _, found := properties[name]; !found {
+				properties[name] = prop
+			}
+		}
+		for _, name := range innerRequired {
+			if _, found := properties[name]; found {
+				*required = append(*required, name)
+			}
+		}
+	}
+}
+
+// JSONSchema returns a JSON Schema document of EnvelopeT. The data of
+// PayloadT1 is checked against the data type of its kind for every
+// registered kind, the data of other kinds is any object.
+func JSONSchema() map[string]interface{} {
+	b := newSchemaBuilder()
+	root := b.schema(reflect.TypeOf(EnvelopeT{}))
+	conditions := []interface{}{}
+	for _, kind := range Kinds() {
+		data := map[string]interface{}{"type": "object"}
+		if kind.Type != nil {
+			data = b.schema(kind.Type)
+		}
+		conditions = append(conditions, map[string]interface{}{
+			"if": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"kind": map[string]interface{}{"const": kind.Name},
+				},
+			},
+			"then": map[string]interface{}{
+				"properties": map[string]interface{}{"data": data},
+			},
+		})
+	}
+	payload := b.definitions[b.names[reflect.TypeOf(PayloadT1{})]].(map[string]interface{})
+	if len(conditions) > 0 {
+		payload["allOf"] = conditions
+	}
+	return map[string]interface{}{
+		"$schema":     JSONSchemaDraft,
+		"$ref":        root["$ref"],
+		"definitions": b.definitions,
+	}
+}
+
+// MarshalJSONSchema is the indented JSON of JSONSchema.
+func MarshalJSONSchema() ([]byte, error) {
+	return json.MarshalIndent(JSONSchema(), "", "  ")
+}
@@ -0,0 +1,227 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type JSONSchemaSuite struct {
+	suite.Suite
+	schema map[string]interface{}
+}
+
+type schemaTestInner struct {
+	Inner string `json:"inner"`
+}
+
+type schemaTestKind struct {
+	schemaTestInner
+	At      time.Time       `json:"at"`
+	Blob    []byte          `json:"blob,omitempty"`
+	Counts  map[string]int  `json:"counts"`
+	Next    *schemaTestKind `json:"next,omitempty"`
+	Skipped string          `json:"-"`
+	Version V               `json:"version" description:"the version"`
+	private int
+}
+
+func (d *schemaTestKind) ToDict() map[string]interface{} {
+	return nil
+}
+
+const schemaTestKindName = "test.json-schema"
+
+func (s *JSONSchemaSuite) SetupSuite() {
+	RegisterKind(Kind{
+		Name: schemaTestKindName,
+		Type: reflect.TypeOf(schemaTestKind{}),
+		FromDict: func(data map[string]interface{}) (KindData, error) {
+			return &schemaTestKind{}, nil
+		},
+	})
+}
+
+func (s *JSONSchemaSuite) TearDownSuite() {
+	UnregisterKind(schemaTestKindName)
+}
+
+func (s *JSONSchemaSuite) SetupTest() {
+	// the document survives a JSON round trip like a published one
+	out, err := MarshalJSONSchema()
+	assert.NoError(s.T(), err)
+	s.schema = map[string]interface{}{}
+	assert.NoError(s.T(), json.Unmarshal(out, &s.schema))
+}
+
+// validate implements the keywords JSONSchema uses.
+func (s *JSONSchemaSuite) validate(schema map[string]interface{}, v interface{}, path string) []string {
+	errs := []string{}
+	if ref, ok := schema["$ref"].(string); ok {
+		def := s.schema["definitions"].(map[string]interface{})[strings.TrimPrefix(ref, "#/definitions/")]
+		return s.validate(def.(map[string]interface{}), v, path)
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			errs = append(errs, s.validate(sub.(map[string]interface{}), v, path)...)
+		}
+	}
+	if cond, ok := schema["if"].(map[string]interface{}); ok {
+		if len(s.validate(cond, v, path)) == 0 {
+			errs = append(errs, s.validate(schema["then"].(map[string]interface{}), v, path)...)
+		}
+	}
+	if c, ok := schema["const"]; ok && c != v {
+		errs = append(errs, fmt.Sprintf("%s: %v is not %v", path, v, c))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			errs = append(errs, fmt.Sprintf("%s: %v not in %v", path, v, enum))
+		}
+	}
+	typ, _ := schema["type"].(string)
+	matches := map[string]bool{
+		"":        true,
+		"object":  reflect.TypeOf(v) == reflect.TypeOf(map[string]interface{}{}),
+		"array":   reflect.TypeOf(v) == reflect.TypeOf([]interface{}{}),
+		"string":  reflect.TypeOf(v) == reflect.TypeOf(""),
+		"boolean": reflect.TypeOf(v) == reflect.TypeOf(true),
+		"number":  reflect.TypeOf(v) == reflect.TypeOf(0.0),
+	}
+	if typ == "integer" {
+		f, ok := v.(float64)
+		matches["integer"] = ok && f == float64(int64(f))
+	}
+	if !matches[typ] {
+		return append(errs, fmt.Sprintf("%s: %v is no %s", path, v, typ))
+	}
+	if obj, ok := v.(map[string]interface{}); ok {
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, found := obj[name.(string)]; !found {
+					errs = append(errs, fmt.Sprintf("%s: %s is missing", path, name))
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		for name, val := range obj {
+			if prop, ok := props[name].(map[string]interface{}); ok {
+				errs = append(errs, s.validate(prop, val, path+"."+name)...)
+			} else if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+				errs = append(errs, s.validate(additional, val, path+"."+name)...)
+			}
+		}
+	}
+	if arr, ok := v.([]interface{}); ok {
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for idx, item := range arr {
+				errs = append(errs, s.validate(items, item, fmt.Sprintf("%s[%d]", path, idx))...)
+			}
+		}
+	}
+	return errs
+}
+
+func (s *JSONSchemaSuite) check(jsonStr string) []string {
+	var v interface{}
+	assert.NoError(s.T(), json.Unmarshal([]byte(jsonStr), &v))
+	return s.validate(s.schema, v, "$")
+}
+
+func (s *JSONSchemaSuite) TestValidEnvelopes() {
+	chunker := NewChunker(ChunkerProps{Src: "test case", ChunkSize: 4, TimeGenerator: mtimer})
+	assert.NoError(s.T(), chunker.Split(bytes.NewReader([]byte("hello")), func(se *SimpleEnvelope) error {
+		assert.Empty(s.T(), s.check(*se.AsJson()))
+		return nil
+	}))
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "unregistered", Data: map[string]interface{}{"any": []interface{}{1, "x"}}},
+		TimeGenerator: mtimer,
+	})
+	env := se.AsEnvelope()
+	env.Signatures = []SignatureT{{Alg: "ES256", Kid: "kid", Src: "test case", T: 1, Sig: "sig", X5C: []string{"x"}}}
+	out, err := env.Marshal()
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), s.check(string(out)))
+}
+
+func (s *JSONSchemaSuite) TestInvalidEnvelopes() {
+	valid := `{"data":{"data":{"data":"aGVs","hash":"h","seq":1,"transfer":"t"},"kind":"c5.chunk"},"dst":[],"id":"id","src":"src","t":1,"ttl":10,"v":"A"}`
+	assert.Empty(s.T(), s.check(valid))
+	for broken, expected := range map[string]string{
+		`"seq":1`:     `"seq":1.5`,
+		`"v":"A"`:     `"v":"B"`,
+		`"dst":[],`:   ``,
+		`"hash":"h",`: `"hash":4,`,
+		`"ttl":10`:    `"ttl":"10"`,
+		`"v":"A"}`:    `"v":"A","signatures":[{"alg":"a","sig":"s","src":"s","t":1}]}`,
+	} {
+		assert.NotEmpty(s.T(), s.check(strings.Replace(valid, broken, expected, 1)), expected)
+	}
+}
+
+func (s *JSONSchemaSuite) TestReflection() {
+	defs := s.schema["definitions"].(map[string]interface{})
+	kind := defs["schemaTestKind"].(map[string]interface{})
+	props := kind["properties"].(map[string]interface{})
+	assert.Equal(s.T(), map[string]interface{}{"type": "string", "format": "date-time"}, props["at"])
+	assert.Equal(s.T(), map[string]interface{}{"type": "string", "contentEncoding": "base64"}, props["blob"])
+	assert.Equal(s.T(), map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "integer"}}, props["counts"])
+	assert.Equal(s.T(), map[string]interface{}{"$ref": "#/definitions/schemaTestKind"}, props["next"])
+	assert.Equal(s.T(), map[string]interface{}{
+		"allOf":       []interface{}{map[string]interface{}{"$ref": "#/definitions/V"}},
+		"description": "the version",
+	}, props["version"])
+	assert.Equal(s.T(), map[string]interface{}{"type": "string"}, props["inner"])
+	assert.NotContains(s.T(), props, "Skipped")
+	assert.NotContains(s.T(), props, "private")
+	assert.Equal(s.T(), []interface{}{"at", "counts", "inner", "version"}, kind["required"])
+	assert.Equal(s.T(), map[string]interface{}{"type": "string", "enum": []interface{}{"A"}}, defs["V"])
+}
+
+// TestSchemaSource compares the reflected types with the schema c5gen
+// generated them from.
+func (s *JSONSchemaSuite) TestSchemaSource() {
+	for _, fname := range []string{"../schema/envelope.schema.json", "../schema/kinds.schema.json"} {
+		src, err := os.ReadFile(fname)
+		assert.NoError(s.T(), err)
+		source := map[string]interface{}{}
+		assert.NoError(s.T(), json.Unmarshal(src, &source))
+		defs := s.schema["definitions"].(map[string]interface{})
+		for name, def := range source["definitions"].(map[string]interface{}) {
+			reflected, found := defs[name].(map[string]interface{})
+			if !found {
+				continue
+			}
+			def := def.(map[string]interface{})
+			assert.ElementsMatch(s.T(), def["required"], reflected["required"], name)
+			props, _ := def["properties"].(map[string]interface{})
+			for prop, sub := range props {
+				sub := sub.(map[string]interface{})
+				rsub := reflected["properties"].(map[string]interface{})[prop].(map[string]interface{})
+				if all, ok := rsub["allOf"].([]interface{}); ok {
+					rsub = all[0].(map[string]interface{})
+				}
+				assert.Equal(s.T(), sub["type"], rsub["type"], "%s.%s", name, prop)
+				assert.Equal(s.T(), sub["$ref"], rsub["$ref"], "%s.%s", name, prop)
+			}
+		}
+	}
+}
+
+func TestJSONSchemaSuite(t *testing.T) {
+	suite.Run(t, new(JSONSchemaSuite))
+}
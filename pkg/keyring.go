@@ -0,0 +1,309 @@
+package c5
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	AlgEdDSA = "EdDSA"
+	AlgES256 = "ES256"
+	AlgES384 = "ES384"
+	AlgRS256 = "RS256"
+)
+
+var (
+	ErrKeyNotFound     = errors.New("key not found")
+	ErrKeyRevoked      = errors.New("key revoked")
+	ErrKeyExpired      = errors.New("key expired")
+	ErrKeyNotYetValid  = errors.New("key not yet valid")
+	ErrKeySrcMismatch  = errors.New("key src mismatch")
+	ErrNoSigningKey    = errors.New("no signing key")
+	ErrUnsupportedKey  = errors.New("unsupported key type")
+	ErrDuplicateKeyID  = errors.New("duplicate key id")
+	ErrKeyIDIsRequired = errors.New("key id is required")
+)
+
+// Key binds a public key (and optionally its private part) to a Src
+// identity and a validity window. A zero NotBefore or NotAfter leaves the
+// window open on that side.
+type Key struct {
+	ID        string
+	Src       string
+	Alg       string
+	Public    crypto.PublicKey
+	Private   crypto.Signer
+	NotBefore time.Time
+	NotAfter  time.Time
+	RevokedAt *time.Time
+}
+
+// GenerateKey creates a fresh key pair for src; alg is one of AlgEdDSA,
+// AlgES256, AlgES384 or AlgRS256. The ID is the JWK thumbprint.
+func GenerateKey(alg string, src string) (*Key, error) {
+	var priv crypto.Signer
+	var err error
+	switch alg {
+	case AlgEdDSA:
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case AlgES256:
+		priv, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgES384:
+		priv, err = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	case AlgRS256:
+		priv, err = rsa.GenerateKey(rand.Reader, 2048)
+	default:
+		return nil, fmt.Errorf("%w: alg %q", ErrUnsupportedKey, alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(priv, src)
+}
+
+// NewKey wraps a public key or crypto.Signer into a Key with the
+// algorithm derived from the key type and the ID set to the JWK thumbprint.
+func NewKey(k interface{}, src string) (*Key, error) {
+	key := &Key{Src: src}
+	switch v := k.(type) {
+	case crypto.Signer:
+		key.Private = v
+		key.Public = v.Public()
+	case crypto.PublicKey:
+		key.Public = v
+	}
+	alg, err := algForPublicKey(key.Public)
+	if err != nil {
+		return nil, err
+	}
+	key.Alg = alg
+	key.ID, err = key.Thumbprint()
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func algForPublicKey(pub crypto.PublicKey) (string, error) {
+	switch v := pub.(type) {
+	case ed25519.PublicKey:
+		return AlgEdDSA, nil
+	case *ecdsa.PublicKey:
+		switch v.Curve {
+		case elliptic.P256():
+			return AlgES256, nil
+		case elliptic.P384():
+			return AlgES384, nil
+		}
+	case *rsa.PublicKey:
+		return AlgRS256, nil
+	}
+	return "", fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
+
+// ValidAt reports why the key can't be used at t, or nil if it can.
+func (k *Key) ValidAt(t time.Time) error {
+	if k.RevokedAt != nil && !t.Before(*k.RevokedAt) {
+		return fmt.Errorf("%w: %s at %s", ErrKeyRevoked, k.ID, k.RevokedAt.Format(JSISOStringMillisFormat))
+	}
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return fmt.Errorf("%w: %s before %s", ErrKeyNotYetValid, k.ID, k.NotBefore.Format(JSISOStringMillisFormat))
+	}
+	if !k.NotAfter.IsZero() && t.After(k.NotAfter) {
+		return fmt.Errorf("%w: %s after %s", ErrKeyExpired, k.ID, k.NotAfter.Format(JSISOStringMillisFormat))
+	}
+	return nil
+}
+
+// PublicOnly returns a copy of the key without the private part.
+func (k *Key) PublicOnly() *Key {
+	ret := *k
+	ret.Private = nil
+	return &ret
+}
+
+// KeyStore is the pluggable backend of a Keyring.
+type KeyStore interface {
+	Get(kid string) (*Key, error)
+	Put(key *Key) error
+	List() ([]*Key, error)
+}
+
+type MemoryKeyStore struct {
+	lock sync.RWMutex
+	keys map[string]*Key
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{
+		keys: map[string]*Key{},
+	}
+}
+
+func (m *MemoryKeyStore) Get(kid string) (*Key, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	key, found := m.keys[kid]
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, kid)
+	}
+	return key, nil
+}
+
+func (m *MemoryKeyStore) Put(key *Key) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.keys[key.ID] = key
+	return nil
+}
+
+func (m *MemoryKeyStore) List() ([]*Key, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	ret := make([]*Key, 0, len(m.keys))
+	for _, key := range m.keys {
+		ret = append(ret, key)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+// Keyring manages keys per Src identity on top of a KeyStore and resolves
+// the key that signed an envelope.
+type Keyring struct {
+	store         KeyStore
+	TimeGenerator TimeGenerator
+}
+
+func NewKeyring(store KeyStore) *Keyring {
+	if store == nil {
+		store = NewMemoryKeyStore()
+	}
+	return &Keyring{
+		store:         store,
+		TimeGenerator: &realTimer{},
+	}
+}
+
+func (kr *Keyring) Store() KeyStore {
+	return kr.store
+}
+
+func (kr *Keyring) Add(key *Key) error {
+	if key.ID == "" {
+		return ErrKeyIDIsRequired
+	}
+	if prev, err := kr.store.Get(key.ID); err == nil && prev.Src != key.Src {
+		return fmt.Errorf("%w: %s is bound to %q", ErrDuplicateKeyID, key.ID, prev.Src)
+	}
+	return kr.store.Put(key)
+}
+
+// Revoke marks the key as revoked from at onwards.
+func (kr *Keyring) Revoke(kid string, at time.Time) error {
+	key, err := kr.store.Get(kid)
+	if err != nil {
+		return err
+	}
+	ret := *key
+	ret.RevokedAt = &at
+	return kr.store.Put(&ret)
+}
+
+// Keys returns all keys of src; an empty src returns every key.
+func (kr *Keyring) Keys(src string) ([]*Key, error) {
+	keys, err := kr.store.List()
+	if err != nil {
+		return nil, err
+	}
+	ret := []*Key{}
+	for _, key := range keys {
+		if src == "" || key.Src == src {
+			ret = append(ret, key)
+		}
+	}
+	return ret, nil
+}
+
+// Lookup returns the key kid if it belongs to src and is valid at t.
+func (kr *Keyring) Lookup(src string, kid string, at time.Time) (*Key, error) {
+	key, err := kr.store.Get(kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Src != src {
+		return nil, fmt.Errorf("%w: %s is bound to %q not %q", ErrKeySrcMismatch, kid, key.Src, src)
+	}
+	if err := key.ValidAt(at); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// Active returns the signing key of src which is valid now and has the
+// most recent NotBefore.
+func (kr *Keyring) Active(src string) (*Key, error) {
+	keys, err := kr.Keys(src)
+	if err != nil {
+		return nil, err
+	}
+	now := kr.TimeGenerator.Now()
+	var active *Key
+	for _, key := range keys {
+		if key.Private == nil || key.ValidAt(now) != nil {
+			continue
+		}
+		if active == nil || key.NotBefore.After(active.NotBefore) {
+			active = key
+		}
+	}
+	if active == nil {
+		return nil, fmt.Errorf("%w: for %q", ErrNoSigningKey, src)
+	}
+	return active, nil
+}
+
+// Rotate adds a copy of next as the new signing key of its Src. Every
+// other key of that Src stays valid for verification for another grace
+// period.
+func (kr *Keyring) Rotate(next *Key, grace time.Duration) error {
+	now := kr.TimeGenerator.Now()
+	keys, err := kr.Keys(next.Src)
+	if err != nil {
+		return err
+	}
+	added := *next
+	if added.NotBefore.IsZero() {
+		added.NotBefore = now
+	}
+	for _, key := range keys {
+		if key.ID == next.ID {
+			continue
+		}
+		notAfter := now.Add(grace)
+		if !key.NotAfter.IsZero() && key.NotAfter.Before(notAfter) {
+			continue
+		}
+		ret := *key
+		ret.NotAfter = notAfter
+		if err := kr.store.Put(&ret); err != nil {
+			return err
+		}
+	}
+	return kr.Add(&added)
+}
+
+// ResolveKey finds the key kid which signed env. The validity window is
+// checked against the envelope's T.
+func (kr *Keyring) ResolveKey(env *EnvelopeT, kid string) (*Key, error) {
+	return kr.Lookup(env.Src, kid, time.UnixMilli(int64(env.T)))
+}
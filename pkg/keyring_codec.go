@@ -0,0 +1,499 @@
+package c5
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWK is the JSON Web Key representation of a Key. The src, nbf, exp and
+// revoked members are c5 extensions; times are unix seconds.
+type JWK struct {
+	Kty     string `json:"kty"`
+	Kid     string `json:"kid,omitempty"`
+	Alg     string `json:"alg,omitempty"`
+	Crv     string `json:"crv,omitempty"`
+	X       string `json:"x,omitempty"`
+	Y       string `json:"y,omitempty"`
+	N       string `json:"n,omitempty"`
+	E       string `json:"e,omitempty"`
+	D       string `json:"d,omitempty"`
+	P       string `json:"p,omitempty"`
+	Q       string `json:"q,omitempty"`
+	Dp      string `json:"dp,omitempty"`
+	Dq      string `json:"dq,omitempty"`
+	Qi      string `json:"qi,omitempty"`
+	Src     string `json:"src,omitempty"`
+	Nbf     int64  `json:"nbf,omitempty"`
+	Exp     int64  `json:"exp,omitempty"`
+	Revoked int64  `json:"revoked,omitempty"`
+}
+
+var b64url = base64.RawURLEncoding
+
+func b64Int(i *big.Int) string {
+	return b64url.EncodeToString(i.Bytes())
+}
+
+func b64Coord(i *big.Int, curve elliptic.Curve) string {
+	size := (curve.Params().BitSize + 7) / 8
+	return b64url.EncodeToString(i.FillBytes(make([]byte, size)))
+}
+
+func unb64Int(s string) (*big.Int, error) {
+	b, err := b64url.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func timeOrZero(secs int64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0)
+}
+
+func curveByName(crv string) (elliptic.Curve, error) {
+	switch crv {
+	case "P-256":
+		return elliptic.P256(), nil
+	case "P-384":
+		return elliptic.P384(), nil
+	}
+	return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, crv)
+}
+
+// publicJWK fills only the members which identify the public key.
+func publicJWK(pub crypto.PublicKey) (*JWK, error) {
+	switch v := pub.(type) {
+	case ed25519.PublicKey:
+		return &JWK{Kty: "OKP", Crv: "Ed25519", X: b64url.EncodeToString(v)}, nil
+	case *ecdsa.PublicKey:
+		return &JWK{
+			Kty: "EC",
+			Crv: v.Curve.Params().Name,
+			X:   b64Coord(v.X, v.Curve),
+			Y:   b64Coord(v.Y, v.Curve),
+		}, nil
+	case *rsa.PublicKey:
+		return &JWK{Kty: "RSA", N: b64Int(v.N), E: b64Int(big.NewInt(int64(v.E)))}, nil
+	}
+	return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, pub)
+}
+
+// Thumbprint computes the RFC 7638 JWK thumbprint of the public key.
+func (k *Key) Thumbprint() (string, error) {
+	jwk, err := publicJWK(k.Public)
+	if err != nil {
+		return "", err
+	}
+	var members map[string]string
+	switch jwk.Kty {
+	case "OKP":
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X}
+	case "EC":
+		members = map[string]string{"crv": jwk.Crv, "kty": jwk.Kty, "x": jwk.X, "y": jwk.Y}
+	case "RSA":
+		members = map[string]string{"e": jwk.E, "kty": jwk.Kty, "n": jwk.N}
+	}
+	// encoding/json sorts map keys, which is the canonical form of RFC 7638
+	b, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return b64url.EncodeToString(sum[:]), nil
+}
+
+func (k *Key) ToJWK(withPrivate bool) (*JWK, error) {
+	jwk, err := publicJWK(k.Public)
+	if err != nil {
+		return nil, err
+	}
+	jwk.Kid = k.ID
+	jwk.Alg = k.Alg
+	jwk.Src = k.Src
+	jwk.Nbf = unixOrZero(k.NotBefore)
+	jwk.Exp = unixOrZero(k.NotAfter)
+	if k.RevokedAt != nil {
+		jwk.Revoked = k.RevokedAt.Unix()
+	}
+	if !withPrivate || k.Private == nil {
+		return jwk, nil
+	}
+	switch v := k.Private.(type) {
+	case ed25519.PrivateKey:
+		jwk.D = b64url.EncodeToString(v.Seed())
+	case *ecdsa.PrivateKey:
+		jwk.D = b64Coord(v.D, v.Curve)
+	case *rsa.PrivateKey:
+		v.Precompute()
+		jwk.D = b64Int(v.D)
+		jwk.P = b64Int(v.Primes[0])
+		jwk.Q = b64Int(v.Primes[1])
+		jwk.Dp = b64Int(v.Precomputed.Dp)
+		jwk.Dq = b64Int(v.Precomputed.Dq)
+		jwk.Qi = b64Int(v.Precomputed.Qinv)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, k.Private)
+	}
+	return jwk, nil
+}
+
+func (k *Key) MarshalJWK(withPrivate bool) ([]byte, error) {
+	jwk, err := k.ToJWK(withPrivate)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jwk)
+}
+
+func (jwk *JWK) ToKey() (*Key, error) {
+	var pub crypto.PublicKey
+	var priv crypto.Signer
+	switch jwk.Kty {
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %q", ErrUnsupportedKey, jwk.Crv)
+		}
+		x, err := b64url.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 public key size:%d", len(x))
+		}
+		pub = ed25519.PublicKey(x)
+		if jwk.D != "" {
+			d, err := b64url.DecodeString(jwk.D)
+			if err != nil {
+				return nil, err
+			}
+			if len(d) != ed25519.SeedSize {
+				return nil, fmt.Errorf("invalid Ed25519 seed size:%d", len(d))
+			}
+			priv = ed25519.NewKeyFromSeed(d)
+		}
+	case "EC":
+		curve, err := curveByName(jwk.Crv)
+		if err != nil {
+			return nil, err
+		}
+		x, err := unb64Int(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := unb64Int(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("%w: point not on curve %s", ErrUnsupportedKey, jwk.Crv)
+		}
+		ecPub := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		pub = ecPub
+		if jwk.D != "" {
+			d, err := unb64Int(jwk.D)
+			if err != nil {
+				return nil, err
+			}
+			if d.Sign() <= 0 || d.Cmp(curve.Params().N) >= 0 {
+				return nil, fmt.Errorf("%w: d out of range", ErrUnsupportedKey)
+			}
+			// d has to be the private part of x and y
+			dx, dy := curve.ScalarBaseMult(d.FillBytes(make([]byte, (curve.Params().BitSize+7)/8)))
+			if dx.Cmp(x) != 0 || dy.Cmp(y) != 0 {
+				return nil, fmt.Errorf("%w: d does not match x and y", ErrUnsupportedKey)
+			}
+			priv = &ecdsa.PrivateKey{PublicKey: *ecPub, D: d}
+		}
+	case "RSA":
+		n, err := unb64Int(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := unb64Int(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		rsaPub := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		pub = rsaPub
+		if jwk.D != "" {
+			vals := make([]*big.Int, 3)
+			for idx, s := range []string{jwk.D, jwk.P, jwk.Q} {
+				vals[idx], err = unb64Int(s)
+				if err != nil {
+					return nil, err
+				}
+			}
+			rsaPriv := &rsa.PrivateKey{PublicKey: *rsaPub, D: vals[0], Primes: []*big.Int{vals[1], vals[2]}}
+			if err := rsaPriv.Validate(); err != nil {
+				return nil, err
+			}
+			rsaPriv.Precompute()
+			priv = rsaPriv
+		}
+	default:
+		return nil, fmt.Errorf("%w: kty %q", ErrUnsupportedKey, jwk.Kty)
+	}
+	var key *Key
+	var err error
+	if priv != nil {
+		key, err = NewKey(priv, jwk.Src)
+	} else {
+		key, err = NewKey(pub, jwk.Src)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if jwk.Kid != "" {
+		key.ID = jwk.Kid
+	}
+	if jwk.Alg != "" {
+		key.Alg = jwk.Alg
+	}
+	key.NotBefore = timeOrZero(jwk.Nbf)
+	key.NotAfter = timeOrZero(jwk.Exp)
+	if jwk.Revoked != 0 {
+		revoked := time.Unix(jwk.Revoked, 0)
+		key.RevokedAt = &revoked
+	}
+	return key, nil
+}
+
+// ParseJWK reads a single JWK or a JWK Set ({"keys":[...]}).
+func ParseJWK(data []byte) ([]*Key, error) {
+	set := struct {
+		Keys []JWK `json:"keys"`
+	}{}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	if set.Keys == nil {
+		jwk := JWK{}
+		if err := json.Unmarshal(data, &jwk); err != nil {
+			return nil, err
+		}
+		set.Keys = []JWK{jwk}
+	}
+	ret := make([]*Key, 0, len(set.Keys))
+	for idx := range set.Keys {
+		key, err := set.Keys[idx].ToKey()
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, key)
+	}
+	return ret, nil
+}
+
+const (
+	pemHeaderKid       = "Kid"
+	pemHeaderSrc       = "Src"
+	pemHeaderNotBefore = "Not-Before"
+	pemHeaderNotAfter  = "Not-After"
+	pemHeaderRevokedAt = "Revoked-At"
+)
+
+// MarshalPEM encodes the key as PKCS#8 or PKIX block; the c5 metadata is
+// stored in the PEM headers.
+func (k *Key) MarshalPEM(withPrivate bool) ([]byte, error) {
+	block := &pem.Block{Headers: map[string]string{}}
+	var err error
+	if withPrivate && k.Private != nil {
+		block.Type = "PRIVATE KEY"
+		block.Bytes, err = x509.MarshalPKCS8PrivateKey(k.Private)
+	} else {
+		block.Type = "PUBLIC KEY"
+		block.Bytes, err = x509.MarshalPKIXPublicKey(k.Public)
+	}
+	if err != nil {
+		return nil, err
+	}
+	block.Headers[pemHeaderKid] = k.ID
+	if k.Src != "" {
+		block.Headers[pemHeaderSrc] = k.Src
+	}
+	if !k.NotBefore.IsZero() {
+		block.Headers[pemHeaderNotBefore] = k.NotBefore.UTC().Format(time.RFC3339)
+	}
+	if !k.NotAfter.IsZero() {
+		block.Headers[pemHeaderNotAfter] = k.NotAfter.UTC().Format(time.RFC3339)
+	}
+	if k.RevokedAt != nil {
+		block.Headers[pemHeaderRevokedAt] = k.RevokedAt.UTC().Format(time.RFC3339)
+	}
+	return pem.EncodeToMemory(block), nil
+}
+
+func pemTime(headers map[string]string, name string) (time.Time, error) {
+	val, found := headers[name]
+	if !found {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
+
+// ParsePEM reads all key blocks of data. Blocks which are not keys are
+// skipped.
+func ParsePEM(data []byte) ([]*Key, error) {
+	ret := []*Key{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		var raw interface{}
+		var err error
+		switch block.Type {
+		case "PRIVATE KEY":
+			raw, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			raw, err = x509.ParseECPrivateKey(block.Bytes)
+		case "RSA PRIVATE KEY":
+			raw, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "PUBLIC KEY":
+			raw, err = x509.ParsePKIXPublicKey(block.Bytes)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		key, err := NewKey(raw, block.Headers[pemHeaderSrc])
+		if err != nil {
+			return nil, err
+		}
+		if kid, found := block.Headers[pemHeaderKid]; found {
+			key.ID = kid
+		}
+		if key.NotBefore, err = pemTime(block.Headers, pemHeaderNotBefore); err != nil {
+			return nil, err
+		}
+		if key.NotAfter, err = pemTime(block.Headers, pemHeaderNotAfter); err != nil {
+			return nil, err
+		}
+		revokedAt, err := pemTime(block.Headers, pemHeaderRevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		if !revokedAt.IsZero() {
+			key.RevokedAt = &revokedAt
+		}
+		ret = append(ret, key)
+	}
+	return ret, nil
+}
+
+// LoadKeyFile reads the keys of a .pem, .jwk or .json file.
+func LoadKeyFile(fname string) ([]*Key, error) {
+	data, err := os.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(fname)) {
+	case ".pem":
+		return ParsePEM(data)
+	case ".jwk", ".json":
+		return ParseJWK(data)
+	}
+	return nil, fmt.Errorf("unknown key file type:%s", fname)
+}
+
+// DirKeyStore keeps the keys as .jwk/.pem files in a directory. Put writes
+// <kid>.jwk, including the private part if present.
+type DirKeyStore struct {
+	dir   string
+	cache *MemoryKeyStore
+	lock  sync.Mutex
+}
+
+func NewDirKeyStore(dir string) (*DirKeyStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	ds := &DirKeyStore{dir: dir, cache: NewMemoryKeyStore()}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".pem", ".jwk", ".json":
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		keys, err := LoadKeyFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, key := range keys {
+			if prev, err := ds.cache.Get(key.ID); err == nil && prev.Private != nil && key.Private == nil {
+				// keep the private part if the public key is also lying around
+				key.Private = prev.Private
+			}
+			ds.cache.Put(key)
+		}
+	}
+	return ds, nil
+}
+
+func (d *DirKeyStore) Get(kid string) (*Key, error) {
+	return d.cache.Get(kid)
+}
+
+func (d *DirKeyStore) List() ([]*Key, error) {
+	return d.cache.List()
+}
+
+func (d *DirKeyStore) Put(key *Key) error {
+	if strings.ContainsAny(key.ID, "/\\") || strings.HasPrefix(key.ID, ".") {
+		return fmt.Errorf("key id not usable as file name:%s", key.ID)
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	data, err := key.MarshalJWK(true)
+	if err != nil {
+		return err
+	}
+	fname := filepath.Join(d.dir, fmt.Sprintf("%s.jwk", key.ID))
+	tmp := fname + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, fname); err != nil {
+		return err
+	}
+	return d.cache.Put(key)
+}
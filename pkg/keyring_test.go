@@ -0,0 +1,202 @@
+package c5
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeyringSuite struct {
+	suite.Suite
+}
+
+func (s *KeyringSuite) TestGenerateKeyThumbprint() {
+	for _, alg := range []string{AlgEdDSA, AlgES256, AlgES384, AlgRS256} {
+		key, err := GenerateKey(alg, "test case")
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), alg, key.Alg)
+		thumb, err := key.Thumbprint()
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), thumb, key.ID)
+	}
+}
+
+func (s *KeyringSuite) TestJWKRoundTrip() {
+	for _, alg := range []string{AlgEdDSA, AlgES256, AlgRS256} {
+		key, err := GenerateKey(alg, "test case")
+		assert.NoError(s.T(), err)
+		key.NotBefore = time.Unix(1000, 0)
+		key.NotAfter = time.Unix(2000, 0)
+		data, err := key.MarshalJWK(true)
+		assert.NoError(s.T(), err)
+		keys, err := ParseJWK(data)
+		assert.NoError(s.T(), err)
+		assert.Len(s.T(), keys, 1)
+		assert.Equal(s.T(), key.ID, keys[0].ID)
+		assert.Equal(s.T(), "test case", keys[0].Src)
+		assert.True(s.T(), key.NotBefore.Equal(keys[0].NotBefore))
+		assert.True(s.T(), key.NotAfter.Equal(keys[0].NotAfter))
+		assert.NotNil(s.T(), keys[0].Private)
+
+		data, err = key.MarshalJWK(false)
+		assert.NoError(s.T(), err)
+		keys, err = ParseJWK(data)
+		assert.NoError(s.T(), err)
+		assert.Nil(s.T(), keys[0].Private)
+		assert.Equal(s.T(), key.ID, keys[0].ID)
+	}
+}
+
+func (s *KeyringSuite) TestPEMRoundTrip() {
+	key, err := GenerateKey(AlgES256, "test case")
+	assert.NoError(s.T(), err)
+	revoked := time.Unix(5000, 0)
+	key.RevokedAt = &revoked
+	priv, err := key.MarshalPEM(true)
+	assert.NoError(s.T(), err)
+	pub, err := key.MarshalPEM(false)
+	assert.NoError(s.T(), err)
+	keys, err := ParsePEM(append(priv, pub...))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), keys, 2)
+	assert.NotNil(s.T(), keys[0].Private)
+	assert.Nil(s.T(), keys[1].Private)
+	for _, k := range keys {
+		assert.Equal(s.T(), key.ID, k.ID)
+		assert.Equal(s.T(), "test case", k.Src)
+		assert.True(s.T(), revoked.Equal(*k.RevokedAt))
+	}
+}
+
+func (s *KeyringSuite) TestLookupValidity() {
+	kr := NewKeyring(nil)
+	key, _ := GenerateKey(AlgEdDSA, "test case")
+	key.NotBefore = time.UnixMilli(1000)
+	key.NotAfter = time.UnixMilli(2000)
+	assert.NoError(s.T(), kr.Add(key))
+
+	_, err := kr.Lookup("test case", key.ID, time.UnixMilli(1500))
+	assert.NoError(s.T(), err)
+	_, err = kr.Lookup("test case", key.ID, time.UnixMilli(500))
+	assert.True(s.T(), errors.Is(err, ErrKeyNotYetValid))
+	_, err = kr.Lookup("test case", key.ID, time.UnixMilli(2500))
+	assert.True(s.T(), errors.Is(err, ErrKeyExpired))
+	_, err = kr.Lookup("other", key.ID, time.UnixMilli(1500))
+	assert.True(s.T(), errors.Is(err, ErrKeySrcMismatch))
+	_, err = kr.Lookup("test case", "unknown", time.UnixMilli(1500))
+	assert.True(s.T(), errors.Is(err, ErrKeyNotFound))
+
+	assert.NoError(s.T(), kr.Revoke(key.ID, time.UnixMilli(1200)))
+	_, err = kr.Lookup("test case", key.ID, time.UnixMilli(1100))
+	assert.NoError(s.T(), err)
+	_, err = kr.Lookup("test case", key.ID, time.UnixMilli(1500))
+	assert.True(s.T(), errors.Is(err, ErrKeyRevoked))
+}
+
+func (s *KeyringSuite) TestJWKInvalidEC() {
+	key, _ := GenerateKey(AlgES256, "test case")
+	other, _ := GenerateKey(AlgES256, "test case")
+	jwk, err := key.ToJWK(true)
+	assert.NoError(s.T(), err)
+	otherJwk, _ := other.ToJWK(true)
+
+	offCurve := *jwk
+	offCurve.D = ""
+	offCurve.Y = otherJwk.Y
+	_, err = offCurve.ToKey()
+	assert.True(s.T(), errors.Is(err, ErrUnsupportedKey))
+
+	mismatch := *jwk
+	mismatch.D = otherJwk.D
+	_, err = mismatch.ToKey()
+	assert.True(s.T(), errors.Is(err, ErrUnsupportedKey))
+
+	zero := *jwk
+	zero.D = "AA"
+	_, err = zero.ToKey()
+	assert.True(s.T(), errors.Is(err, ErrUnsupportedKey))
+
+	_, err = jwk.ToKey()
+	assert.NoError(s.T(), err)
+}
+
+func (s *KeyringSuite) TestRotate() {
+	kr := NewKeyring(nil)
+	kr.TimeGenerator = mtimer
+	now := mtimer.Now()
+	first, _ := GenerateKey(AlgEdDSA, "test case")
+	first.NotBefore = now.Add(-time.Hour)
+	assert.NoError(s.T(), kr.Add(first))
+	active, err := kr.Active("test case")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), first.ID, active.ID)
+
+	second, _ := GenerateKey(AlgEdDSA, "test case")
+	assert.NoError(s.T(), kr.Rotate(second, time.Minute))
+	// the caller's key is left alone
+	assert.True(s.T(), second.NotBefore.IsZero())
+	active, err = kr.Active("test case")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), second.ID, active.ID)
+
+	_, err = kr.Lookup("test case", first.ID, now.Add(30*time.Second))
+	assert.NoError(s.T(), err)
+	_, err = kr.Lookup("test case", first.ID, now.Add(2*time.Minute))
+	assert.True(s.T(), errors.Is(err, ErrKeyExpired))
+
+	_, err = kr.Active("other")
+	assert.True(s.T(), errors.Is(err, ErrNoSigningKey))
+}
+
+func (s *KeyringSuite) TestResolveKey() {
+	kr := NewKeyring(nil)
+	key, _ := GenerateKey(AlgEdDSA, "test case")
+	key.NotBefore = time.UnixMilli(1000)
+	assert.NoError(s.T(), kr.Add(key))
+	env := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:  "test case",
+		T:    4711,
+		Data: PayloadT1{Kind: "kind", Data: map[string]interface{}{"y": 4}},
+	}).AsEnvelope()
+	found, err := kr.ResolveKey(env, key.ID)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), key.ID, found.ID)
+	env.T = 500
+	_, err = kr.ResolveKey(env, key.ID)
+	assert.True(s.T(), errors.Is(err, ErrKeyNotYetValid))
+}
+
+func (s *KeyringSuite) TestDirKeyStore() {
+	dir := s.T().TempDir()
+	key, _ := GenerateKey(AlgEdDSA, "test case")
+	other, _ := GenerateKey(AlgES256, "other")
+	pub, _ := other.MarshalPEM(false)
+	assert.NoError(s.T(), os.WriteFile(filepath.Join(dir, "other.pem"), pub, 0600))
+
+	store, err := NewDirKeyStore(dir)
+	assert.NoError(s.T(), err)
+	kr := NewKeyring(store)
+	assert.NoError(s.T(), kr.Add(key))
+
+	reloaded, err := NewDirKeyStore(dir)
+	assert.NoError(s.T(), err)
+	keys, err := reloaded.List()
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), keys, 2)
+	found, err := reloaded.Get(key.ID)
+	assert.NoError(s.T(), err)
+	assert.NotNil(s.T(), found.Private)
+	found, err = reloaded.Get(other.ID)
+	assert.NoError(s.T(), err)
+	assert.Nil(s.T(), found.Private)
+	assert.Equal(s.T(), "other", found.Src)
+}
+
+func TestKeyringSuite(t *testing.T) {
+	suite.Run(t, new(KeyringSuite))
+}
@@ -0,0 +1,79 @@
+package c5
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+//go:generate go run ../cmd/c5gen -package c5 -o envelope.go ../schema/envelope.schema.json
+//go:generate go run ../cmd/c5gen -package c5 -o payload_kinds.go ../schema/kinds.schema.json
+
+var ErrKindUnknown = errors.New("kind not registered")
+
+// KindData is the typed data of a registered kind.
+type KindData interface {
+	ToDict() map[string]interface{}
+}
+
+// Kind describes the data of a payload kind, c5gen generates and registers
+// them for every schema definition with "x-kind".
+type Kind struct {
+	Name string
+	// Type is the struct type of the data
+	Type     reflect.Type
+	FromDict func(data map[string]interface{}) (KindData, error)
+}
+
+var kinds = struct {
+	lock  sync.RWMutex
+	kinds map[string]Kind
+}{kinds: map[string]Kind{}}
+
+// RegisterKind makes kind known to DecodeKind, it panics if the name is
+// registered already.
+func RegisterKind(kind Kind) {
+	kinds.lock.Lock()
+	defer kinds.lock.Unlock()
+	if _, found := kinds.kinds[kind.Name]; found {
+		panic(fmt.Sprintf("kind %s registered twice", kind.Name))
+	}
+	kinds.kinds[kind.Name] = kind
+}
+
+// UnregisterKind forgets the kind name, unknown names are ignored.
+func UnregisterKind(name string) {
+	kinds.lock.Lock()
+	defer kinds.lock.Unlock()
+	delete(kinds.kinds, name)
+}
+
+func LookupKind(name string) (Kind, bool) {
+	kinds.lock.RLock()
+	defer kinds.lock.RUnlock()
+	kind, found := kinds.kinds[name]
+	return kind, found
+}
+
+// Kinds are all registered kinds sorted by name.
+func Kinds() []Kind {
+	kinds.lock.RLock()
+	defer kinds.lock.RUnlock()
+	ret := make([]Kind, 0, len(kinds.kinds))
+	for _, kind := range kinds.kinds {
+		ret = append(ret, kind)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// DecodeKind returns the typed data of payload.
+func DecodeKind(payload PayloadT1) (KindData, error) {
+	kind, found := LookupKind(payload.Kind)
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrKindUnknown, payload.Kind)
+	}
+	return kind.FromDict(payload.Data)
+}
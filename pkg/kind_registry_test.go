@@ -0,0 +1,76 @@
+package c5
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type KindRegistrySuite struct {
+	suite.Suite
+}
+
+func (s *KindRegistrySuite) TestBuiltinKinds() {
+	names := []string{}
+	for _, kind := range Kinds() {
+		names = append(names, kind.Name)
+	}
+	assert.Equal(s.T(), []string{ChunkKind, TransferKind, TreeHeadKind}, names)
+	kind, found := LookupKind(TreeHeadKind)
+	assert.True(s.T(), found)
+	assert.Equal(s.T(), reflect.TypeOf(TreeHeadT{}), kind.Type)
+}
+
+func (s *KindRegistrySuite) TestDecodeKind() {
+	chunker := NewChunker(ChunkerProps{Src: "test case", ChunkSize: 4, TimeGenerator: mtimer})
+	envs := []*EnvelopeT{}
+	assert.NoError(s.T(), chunker.Split(bytes.NewReader([]byte("hello")), func(se *SimpleEnvelope) error {
+		env, err := UnmarshalEnvelopeT([]byte(*se.AsJson()))
+		envs = append(envs, env)
+		return err
+	}))
+	assert.Len(s.T(), envs, 3)
+	data, err := DecodeKind(envs[1].Data)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), &ChunkT{Transfer: chunker.TransferID(), Seq: 1, Hash: data.(*ChunkT).Hash, Data: "bw=="}, data)
+	data, err = DecodeKind(envs[2].Data)
+	assert.NoError(s.T(), err)
+	transfer := data.(*TransferT)
+	assert.Equal(s.T(), int64(2), transfer.Chunks)
+	assert.Equal(s.T(), int64(5), transfer.Size)
+
+	// the typed payload hashes like the one of the chunker
+	payload := transfer.Payload()
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{Src: "test case", Data: payload, TimeGenerator: mtimer})
+	assert.Equal(s.T(), envs[2].ID, se.AsEnvelope().ID)
+}
+
+func (s *KindRegistrySuite) TestErrors() {
+	_, err := DecodeKind(PayloadT1{Kind: "unknown", Data: map[string]interface{}{}})
+	assert.True(s.T(), errors.Is(err, ErrKindUnknown))
+	_, err = DecodeKind(PayloadT1{Kind: TreeHeadKind, Data: map[string]interface{}{"root": "x"}})
+	assert.True(s.T(), errors.Is(err, ErrDictMissing))
+	_, err = DecodeKind(PayloadT1{Kind: TreeHeadKind, Data: map[string]interface{}{"root": "x", "size": 1.5}})
+	assert.True(s.T(), errors.Is(err, ErrDictType))
+	assert.Panics(s.T(), func() {
+		RegisterKind(Kind{Name: TreeHeadKind})
+	})
+}
+
+func (s *KindRegistrySuite) TestUnregister() {
+	RegisterKind(Kind{Name: "test.unregister"})
+	_, found := LookupKind("test.unregister")
+	assert.True(s.T(), found)
+	UnregisterKind("test.unregister")
+	_, found = LookupKind("test.unregister")
+	assert.False(s.T(), found)
+	UnregisterKind("test.unregister")
+}
+
+func TestKindRegistrySuite(t *testing.T) {
+	suite.Run(t, new(KindRegistrySuite))
+}
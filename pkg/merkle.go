@@ -0,0 +1,154 @@
+package c5
+
+import (
+	"bytes"
+	"crypto/sha256"
+)
+
+// The Merkle tree follows RFC 6962 (Certificate Transparency): leaves are
+// hashed with a 0x00 prefix, inner nodes with 0x01, so a leaf can never be
+// passed off as a node.
+
+func merkleLeafHash(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func merkleNodeHash(left []byte, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{1})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// merkleSplit is the largest power of two smaller than n.
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// merkleRoot is MTH over already leaf-hashed entries.
+func merkleRoot(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		sum := sha256.Sum256(nil)
+		return sum[:]
+	case 1:
+		return leaves[0]
+	}
+	k := merkleSplit(len(leaves))
+	return merkleNodeHash(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+// merkleInclusionPath is the audit path of leaf idx, leaf side first.
+func merkleInclusionPath(leaves [][]byte, idx int) [][]byte {
+	if len(leaves) <= 1 {
+		return [][]byte{}
+	}
+	k := merkleSplit(len(leaves))
+	if idx < k {
+		return append(merkleInclusionPath(leaves[:k], idx), merkleRoot(leaves[k:]))
+	}
+	return append(merkleInclusionPath(leaves[k:], idx-k), merkleRoot(leaves[:k]))
+}
+
+// verifyMerkleInclusion is the verification algorithm of RFC 9162 2.1.3.2.
+func verifyMerkleInclusion(leafHash []byte, idx int, size int, path [][]byte, root []byte) bool {
+	if idx < 0 || idx >= size {
+		return false
+	}
+	fn := idx
+	sn := size - 1
+	r := leafHash
+	for _, p := range path {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = merkleNodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(r, root)
+}
+
+func merkleSubproof(m int, leaves [][]byte, complete bool) [][]byte {
+	n := len(leaves)
+	if m == n {
+		if complete {
+			return [][]byte{}
+		}
+		return [][]byte{merkleRoot(leaves)}
+	}
+	k := merkleSplit(n)
+	if m <= k {
+		return append(merkleSubproof(m, leaves[:k], complete), merkleRoot(leaves[k:]))
+	}
+	return append(merkleSubproof(m-k, leaves[k:], false), merkleRoot(leaves[:k]))
+}
+
+// merkleConsistencyPath is PROOF(m, D[n]) of RFC 6962 2.1.2, it shows that
+// the first m leaves are a prefix of leaves.
+func merkleConsistencyPath(m int, leaves [][]byte) [][]byte {
+	if m <= 0 || m >= len(leaves) {
+		return [][]byte{}
+	}
+	return merkleSubproof(m, leaves, true)
+}
+
+// verifyMerkleConsistency is the verification algorithm of RFC 9162
+// 2.1.4.2. An empty tree is consistent with every tree.
+func verifyMerkleConsistency(first int, second int, path [][]byte, firstRoot []byte, secondRoot []byte) bool {
+	switch {
+	case first < 0 || first > second:
+		return false
+	case first == 0:
+		return len(path) == 0
+	case first == second:
+		return len(path) == 0 && bytes.Equal(firstRoot, secondRoot)
+	case len(path) == 0:
+		return false
+	}
+	if first&(first-1) == 0 {
+		path = append([][]byte{firstRoot}, path...)
+	}
+	fn := first - 1
+	sn := second - 1
+	for fn&1 == 1 {
+		fn >>= 1
+		sn >>= 1
+	}
+	fr := path[0]
+	sr := path[0]
+	for _, c := range path[1:] {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			fr = merkleNodeHash(c, fr)
+			sr = merkleNodeHash(c, sr)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			sr = merkleNodeHash(sr, c)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && bytes.Equal(fr, firstRoot) && bytes.Equal(sr, secondRoot)
+}
@@ -0,0 +1,68 @@
+package c5
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MerkleSuite struct {
+	suite.Suite
+}
+
+func merkleTestLeaves(n int) [][]byte {
+	leaves := make([][]byte, n)
+	for i := range leaves {
+		leaves[i] = merkleLeafHash([]byte(fmt.Sprintf("leaf-%d", i)))
+	}
+	return leaves
+}
+
+func (s *MerkleSuite) TestEmptyRoot() {
+	assert.Equal(s.T(),
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		hex.EncodeToString(merkleRoot(nil)))
+}
+
+func (s *MerkleSuite) TestInclusionAllSizes() {
+	for size := 1; size <= 33; size++ {
+		leaves := merkleTestLeaves(size)
+		root := merkleRoot(leaves)
+		for idx := 0; idx < size; idx++ {
+			path := merkleInclusionPath(leaves, idx)
+			assert.True(s.T(), verifyMerkleInclusion(leaves[idx], idx, size, path, root), "size=%d idx=%d", size, idx)
+			if size > 1 {
+				other := (idx + 1) % size
+				assert.False(s.T(), verifyMerkleInclusion(leaves[other], idx, size, path, root), "size=%d idx=%d", size, idx)
+			}
+		}
+	}
+}
+
+func (s *MerkleSuite) TestLeafIsNotANode() {
+	leaves := merkleTestLeaves(2)
+	inner := append(append([]byte{}, leaves[0]...), leaves[1]...)
+	assert.NotEqual(s.T(), merkleRoot(leaves), merkleLeafHash(inner))
+}
+
+func (s *MerkleSuite) TestConsistencyAllSizes() {
+	leaves := merkleTestLeaves(33)
+	for second := 1; second <= len(leaves); second++ {
+		secondRoot := merkleRoot(leaves[:second])
+		for first := 0; first <= second; first++ {
+			firstRoot := merkleRoot(leaves[:first])
+			path := merkleConsistencyPath(first, leaves[:second])
+			assert.True(s.T(), verifyMerkleConsistency(first, second, path, firstRoot, secondRoot), "first=%d second=%d", first, second)
+			if first > 0 && first < second {
+				assert.False(s.T(), verifyMerkleConsistency(first, second, path, secondRoot, secondRoot), "first=%d second=%d", first, second)
+			}
+		}
+	}
+}
+
+func TestMerkleSuite(t *testing.T) {
+	suite.Run(t, new(MerkleSuite))
+}
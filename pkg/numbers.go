@@ -0,0 +1,193 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Canonical numbers
+//
+// Numbers in the data are hashed and rendered in one canonical form, so a
+// value hashes the same no matter if it is an int, a float64 after a JSON
+// round trip or a json.Number:
+//
+//   - integers (int*, uint*, *big.Int, integral *big.Float and json.Number
+//     integer literals) are their exact decimal digits, "-0" is "0"
+//   - everything else is the ECMAScript Number::toString form of the
+//     shortest decimal that round trips, which is what JSON.stringify in
+//     TypeScript emits: plain digits for 1e-6 <= |x| < 1e21, otherwise
+//     d.ddde+N / d.ddde-N
+//   - json.Number literals with fraction or exponent are parsed as
+//     float64 first, like TypeScript and Python do
+//
+// This only differs from the former fmt.Sprintf("%v") hash input for
+// floats with large or small exponents, e.g. float64(1e6) was "1e+06" and
+// is now "1000000" like the int 1000000. NaN and Inf are not canonical and
+// fail like before.
+
+// formatDecimal renders the value 0.digits * 10^dp.
+func formatDecimal(neg bool, digits string, dp int) string {
+	digits = strings.TrimRight(digits, "0")
+	if digits == "" {
+		return "0"
+	}
+	var b strings.Builder
+	if neg {
+		b.WriteByte('-')
+	}
+	k := len(digits)
+	switch {
+	case k <= dp && dp <= 21:
+		b.WriteString(digits)
+		b.WriteString(strings.Repeat("0", dp-k))
+	case 0 < dp && dp <= 21:
+		b.WriteString(digits[:dp])
+		b.WriteByte('.')
+		b.WriteString(digits[dp:])
+	case -6 < dp && dp <= 0:
+		b.WriteString("0.")
+		b.WriteString(strings.Repeat("0", -dp))
+		b.WriteString(digits)
+	default:
+		b.WriteByte(digits[0])
+		if k > 1 {
+			b.WriteByte('.')
+			b.WriteString(digits[1:])
+		}
+		b.WriteByte('e')
+		if dp-1 >= 0 {
+			b.WriteByte('+')
+		}
+		b.WriteString(strconv.Itoa(dp - 1))
+	}
+	return b.String()
+}
+
+// formatExponent converts the d.ddde±N output of strconv or math/big.
+func formatExponent(str string) string {
+	neg := strings.HasPrefix(str, "-")
+	str = strings.TrimPrefix(str, "-")
+	epos := strings.IndexAny(str, "eE")
+	exp, _ := strconv.Atoi(str[epos+1:])
+	return formatDecimal(neg, strings.Replace(str[:epos], ".", "", 1), exp+1)
+}
+
+// appendCanonicalFloat is canonicalFloat without allocation for the
+// common plain digit range.
+func appendCanonicalFloat(dst []byte, f float64, bitSize int) ([]byte, bool) {
+	abs := math.Abs(f)
+	switch {
+	case abs == 0:
+		return append(dst, '0'), true
+	case abs >= 1e-6 && abs < 1e21:
+		return strconv.AppendFloat(dst, f, 'f', -1, bitSize), true
+	}
+	str, ok := canonicalFloat(f, bitSize)
+	return append(dst, str...), ok
+}
+
+func canonicalFloat(f float64, bitSize int) (string, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", false
+	}
+	return formatExponent(strconv.FormatFloat(f, 'e', -1, bitSize)), true
+}
+
+func canonicalInteger(str string) string {
+	neg := strings.HasPrefix(str, "-")
+	str = strings.TrimLeft(strings.TrimPrefix(str, "-"), "0")
+	if str == "" {
+		return "0"
+	}
+	if neg {
+		return "-" + str
+	}
+	return str
+}
+
+func isIntegerLiteral(str string) bool {
+	str = strings.TrimPrefix(str, "-")
+	if str == "" {
+		return false
+	}
+	for _, c := range str {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// CanonicalNumber returns the canonical form of a number value, ok is
+// false for non numbers, NaN and Inf.
+func CanonicalNumber(v interface{}) (json.Number, bool) {
+	switch n := v.(type) {
+	case int:
+		return json.Number(strconv.FormatInt(int64(n), 10)), true
+	case int8:
+		return json.Number(strconv.FormatInt(int64(n), 10)), true
+	case int16:
+		return json.Number(strconv.FormatInt(int64(n), 10)), true
+	case int32:
+		return json.Number(strconv.FormatInt(int64(n), 10)), true
+	case int64:
+		return json.Number(strconv.FormatInt(n, 10)), true
+	case uint:
+		return json.Number(strconv.FormatUint(uint64(n), 10)), true
+	case uint8:
+		return json.Number(strconv.FormatUint(uint64(n), 10)), true
+	case uint16:
+		return json.Number(strconv.FormatUint(uint64(n), 10)), true
+	case uint32:
+		return json.Number(strconv.FormatUint(uint64(n), 10)), true
+	case uint64:
+		return json.Number(strconv.FormatUint(n, 10)), true
+	case float32:
+		str, ok := canonicalFloat(float64(n), 32)
+		return json.Number(str), ok
+	case float64:
+		str, ok := canonicalFloat(n, 64)
+		return json.Number(str), ok
+	case json.Number:
+		if isIntegerLiteral(string(n)) {
+			return json.Number(canonicalInteger(string(n))), true
+		}
+		f, err := strconv.ParseFloat(string(n), 64)
+		if err != nil {
+			return "", false
+		}
+		return CanonicalNumber(f)
+	case *big.Int:
+		if n == nil {
+			return "", false
+		}
+		return json.Number(n.String()), true
+	case *big.Float:
+		if n == nil || n.IsInf() {
+			return "", false
+		}
+		if n.IsInt() {
+			i, _ := n.Int(nil)
+			return json.Number(i.String()), true
+		}
+		return json.Number(formatExponent(n.Text('e', -1))), true
+	}
+	return "", false
+}
+
+// UnmarshalEnvelopeTUseNumber is UnmarshalEnvelopeT but keeps the numbers
+// in the data as json.Number, so integers above 2^53 are exact.
+func UnmarshalEnvelopeTUseNumber(data []byte) (*EnvelopeT, error) {
+	dict := map[string]interface{}{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&dict); err != nil {
+		return nil, err
+	}
+	ins := EnvelopeT{}
+	return &ins, FromDictEnvelopeT(dict, &ins)
+}
@@ -0,0 +1,120 @@
+package c5
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type NumbersSuite struct {
+	suite.Suite
+}
+
+func (s *NumbersSuite) TestCanonicalNumber() {
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	bigFloat, _ := new(big.Float).SetPrec(200).SetString("0.1000000000000000000001")
+	for _, c := range []struct {
+		in  interface{}
+		out string
+	}{
+		{0, "0"},
+		{-17, "-17"},
+		{int64(math.MaxInt64), "9223372036854775807"},
+		{uint64(math.MaxUint64), "18446744073709551615"},
+		{uint8(7), "7"},
+		{float64(0), "0"},
+		{math.Copysign(0, -1), "0"},
+		{1.5, "1.5"},
+		{float64(1e6), "1000000"},
+		{float64(1 << 60), "1152921504606847000"},
+		{1e20, "100000000000000000000"},
+		{1e21, "1e+21"},
+		{1.5e300, "1.5e+300"},
+		{0.000001, "0.000001"},
+		{0.0000001, "1e-7"},
+		{-1.25e-9, "-1.25e-9"},
+		{float32(0.1), "0.1"},
+		{json.Number("9007199254740993"), "9007199254740993"},
+		{json.Number("-0"), "0"},
+		{json.Number("1.0"), "1"},
+		{json.Number("1E3"), "1000"},
+		{json.Number("2.50e-1"), "0.25"},
+		{bigInt, "123456789012345678901234567890"},
+		{new(big.Float).SetFloat64(1e30), "1000000000000000019884624838656"},
+		{bigFloat, "0.1000000000000000000001"},
+		{big.NewFloat(1.5), "1.5"},
+	} {
+		num, ok := CanonicalNumber(c.in)
+		assert.True(s.T(), ok, "%v", c.in)
+		assert.Equal(s.T(), json.Number(c.out), num, "%T %v", c.in, c.in)
+	}
+	for _, in := range []interface{}{math.NaN(), math.Inf(1), "1", json.Number("x"), (*big.Int)(nil)} {
+		_, ok := CanonicalNumber(in)
+		assert.False(s.T(), ok, "%v", in)
+	}
+}
+
+func (s *NumbersSuite) envelope(data map[string]interface{}) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "numbers", Data: data},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *NumbersSuite) TestSameHashForSameNumber() {
+	id := s.envelope(map[string]interface{}{"n": 1000000, "f": 0.5}).AsEnvelope().ID
+	for _, data := range []map[string]interface{}{
+		{"n": float64(1e6), "f": 0.5},
+		{"n": int64(1000000), "f": float32(0.5)},
+		{"n": json.Number("1000000"), "f": json.Number("5e-1")},
+		{"n": big.NewInt(1000000), "f": big.NewFloat(0.5)},
+	} {
+		assert.Equal(s.T(), id, s.envelope(data).AsEnvelope().ID, "%v", data)
+	}
+}
+
+func (s *NumbersSuite) TestRoundTrip() {
+	se := s.envelope(map[string]interface{}{
+		"big":   uint64(9007199254740993),
+		"large": float64(1e6),
+		"small": 1e-7,
+		"list":  []int{1, 2, 3},
+	})
+	js := *se.AsJson()
+	assert.Contains(s.T(), js, `"big":9007199254740993`)
+	assert.Contains(s.T(), js, `"large":1000000`)
+	assert.Contains(s.T(), js, `"small":1e-7`)
+
+	exact, err := UnmarshalEnvelopeTUseNumber([]byte(js))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), json.Number("9007199254740993"), exact.Data.Data["big"])
+	assert.NoError(s.T(), VerifyID(exact))
+	assert.Equal(s.T(), js, canonicalJson(exact))
+
+	// float64 decoding loses the precision of big and changes the hash
+	lossy, err := UnmarshalEnvelopeT([]byte(js))
+	assert.NoError(s.T(), err)
+	assert.Error(s.T(), VerifyID(lossy))
+}
+
+func (s *NumbersSuite) TestFastPath() {
+	bigInt, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	se := s.envelope(map[string]interface{}{
+		"a": json.Number("12345678901234567890"),
+		"b": bigInt,
+		"c": []float64{1e6, 1e-7, 1e21},
+		"d": uint16(4),
+		"e": math.Copysign(0, -1),
+	})
+	fast := s.envelope(se.AsEnvelope().Data.Data)
+	assert.Equal(s.T(), *se.AsJson(), string(fast.AppendJson(nil)))
+}
+
+func TestNumbersSuite(t *testing.T) {
+	suite.Run(t, new(NumbersSuite))
+}
@@ -0,0 +1,246 @@
+// Code generated by c5gen from kinds.schema.json. DO NOT EDIT.
+
+package c5
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// ChunkT is a part of a transfer, see Chunker
+type ChunkT struct {
+	// base64 of the chunk
+	Data string `json:"data" description:"base64 of the chunk"`
+	// base58 sha256 of the chunk
+	Hash     string `json:"hash" description:"base58 sha256 of the chunk"`
+	Seq      int64  `json:"seq"`
+	Transfer string `json:"transfer"`
+}
+
+func (r *ChunkT) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalChunkT(data []byte) (*ChunkT, error) {
+	dict := map[string]interface{}{}
+	err := json.Unmarshal(data, &dict)
+	if err != nil {
+		return nil, err
+	}
+	ins := ChunkT{}
+	return &ins, FromDictChunkT(dict, &ins)
+}
+
+// Payload is the payload of kind "c5.chunk" with r as data.
+func (r *ChunkT) Payload() PayloadT1 {
+	return PayloadT1{Kind: "c5.chunk", Data: r.ToDict()}
+}
+
+func (r *ChunkT) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	dict["data"] = r.Data
+	dict["hash"] = r.Hash
+	dict["seq"] = r.Seq
+	dict["transfer"] = r.Transfer
+	return dict
+}
+
+func FromDictChunkT(data map[string]interface{}, r *ChunkT) error {
+	if v, ok := data["data"]; ok && v != nil {
+		tmp, err := AsString(v)
+		if err != nil {
+			return fmt.Errorf("ChunkT.data: %w", err)
+		}
+		r.Data = tmp
+	} else {
+		return fmt.Errorf("%w: ChunkT.data", ErrDictMissing)
+	}
+	if v, ok := data["hash"]; ok && v != nil {
+		tmp, err := AsString(v)
+		if err != nil {
+			return fmt.Errorf("ChunkT.hash: %w", err)
+		}
+		r.Hash = tmp
+	} else {
+		return fmt.Errorf("%w: ChunkT.hash", ErrDictMissing)
+	}
+	if v, ok := data["seq"]; ok && v != nil {
+		tmp, err := AsInt64(v)
+		if err != nil {
+			return fmt.Errorf("ChunkT.seq: %w", err)
+		}
+		r.Seq = tmp
+	} else {
+		return fmt.Errorf("%w: ChunkT.seq", ErrDictMissing)
+	}
+	if v, ok := data["transfer"]; ok && v != nil {
+		tmp, err := AsString(v)
+		if err != nil {
+			return fmt.Errorf("ChunkT.transfer: %w", err)
+		}
+		r.Transfer = tmp
+	} else {
+		return fmt.Errorf("%w: ChunkT.transfer", ErrDictMissing)
+	}
+	return nil
+}
+
+// TransferT closes a transfer, see Chunker
+type TransferT struct {
+	Chunks int64 `json:"chunks"`
+	// base58 sha256 of the whole transfer
+	Hash     string `json:"hash" description:"base58 sha256 of the whole transfer"`
+	Size     int64  `json:"size"`
+	Transfer string `json:"transfer"`
+}
+
+func (r *TransferT) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalTransferT(data []byte) (*TransferT, error) {
+	dict := map[string]interface{}{}
+	err := json.Unmarshal(data, &dict)
+	if err != nil {
+		return nil, err
+	}
+	ins := TransferT{}
+	return &ins, FromDictTransferT(dict, &ins)
+}
+
+// Payload is the payload of kind "c5.transfer" with r as data.
+func (r *TransferT) Payload() PayloadT1 {
+	return PayloadT1{Kind: "c5.transfer", Data: r.ToDict()}
+}
+
+func (r *TransferT) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	dict["chunks"] = r.Chunks
+	dict["hash"] = r.Hash
+	dict["size"] = r.Size
+	dict["transfer"] = r.Transfer
+	return dict
+}
+
+func FromDictTransferT(data map[string]interface{}, r *TransferT) error {
+	if v, ok := data["chunks"]; ok && v != nil {
+		tmp, err := AsInt64(v)
+		if err != nil {
+			return fmt.Errorf("TransferT.chunks: %w", err)
+		}
+		r.Chunks = tmp
+	} else {
+		return fmt.Errorf("%w: TransferT.chunks", ErrDictMissing)
+	}
+	if v, ok := data["hash"]; ok && v != nil {
+		tmp, err := AsString(v)
+		if err != nil {
+			return fmt.Errorf("TransferT.hash: %w", err)
+		}
+		r.Hash = tmp
+	} else {
+		return fmt.Errorf("%w: TransferT.hash", ErrDictMissing)
+	}
+	if v, ok := data["size"]; ok && v != nil {
+		tmp, err := AsInt64(v)
+		if err != nil {
+			return fmt.Errorf("TransferT.size: %w", err)
+		}
+		r.Size = tmp
+	} else {
+		return fmt.Errorf("%w: TransferT.size", ErrDictMissing)
+	}
+	if v, ok := data["transfer"]; ok && v != nil {
+		tmp, err := AsString(v)
+		if err != nil {
+			return fmt.Errorf("TransferT.transfer: %w", err)
+		}
+		r.Transfer = tmp
+	} else {
+		return fmt.Errorf("%w: TransferT.transfer", ErrDictMissing)
+	}
+	return nil
+}
+
+// TreeHeadT is the signed tree head of a TransparencyLog
+type TreeHeadT struct {
+	// base58 Merkle tree root
+	Root string `json:"root" description:"base58 Merkle tree root"`
+	Size int64  `json:"size"`
+}
+
+func (r *TreeHeadT) Marshal() ([]byte, error) {
+	return json.Marshal(r)
+}
+
+func UnmarshalTreeHeadT(data []byte) (*TreeHeadT, error) {
+	dict := map[string]interface{}{}
+	err := json.Unmarshal(data, &dict)
+	if err != nil {
+		return nil, err
+	}
+	ins := TreeHeadT{}
+	return &ins, FromDictTreeHeadT(dict, &ins)
+}
+
+// Payload is the payload of kind "c5.tree-head" with r as data.
+func (r *TreeHeadT) Payload() PayloadT1 {
+	return PayloadT1{Kind: "c5.tree-head", Data: r.ToDict()}
+}
+
+func (r *TreeHeadT) ToDict() map[string]interface{} {
+	dict := map[string]interface{}{}
+	dict["root"] = r.Root
+	dict["size"] = r.Size
+	return dict
+}
+
+func FromDictTreeHeadT(data map[string]interface{}, r *TreeHeadT) error {
+	if v, ok := data["root"]; ok && v != nil {
+		tmp, err := AsString(v)
+		if err != nil {
+			return fmt.Errorf("TreeHeadT.root: %w", err)
+		}
+		r.Root = tmp
+	} else {
+		return fmt.Errorf("%w: TreeHeadT.root", ErrDictMissing)
+	}
+	if v, ok := data["size"]; ok && v != nil {
+		tmp, err := AsInt64(v)
+		if err != nil {
+			return fmt.Errorf("TreeHeadT.size: %w", err)
+		}
+		r.Size = tmp
+	} else {
+		return fmt.Errorf("%w: TreeHeadT.size", ErrDictMissing)
+	}
+	return nil
+}
+
+func init() {
+	RegisterKind(Kind{
+		Name: "c5.chunk",
+		Type: reflect.TypeOf(ChunkT{}),
+		FromDict: func(data map[string]interface{}) (KindData, error) {
+			r := &ChunkT{}
+			return r, FromDictChunkT(data, r)
+		},
+	})
+	RegisterKind(Kind{
+		Name: "c5.transfer",
+		Type: reflect.TypeOf(TransferT{}),
+		FromDict: func(data map[string]interface{}) (KindData, error) {
+			r := &TransferT{}
+			return r, FromDictTransferT(data, r)
+		},
+	})
+	RegisterKind(Kind{
+		Name: "c5.tree-head",
+		Type: reflect.TypeOf(TreeHeadT{}),
+		FromDict: func(data map[string]interface{}) (KindData, error) {
+			r := &TreeHeadT{}
+			return r, FromDictTreeHeadT(data, r)
+		},
+	})
+}
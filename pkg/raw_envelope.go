@@ -0,0 +1,143 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+type rawPayload struct {
+	Kind string          `json:"kind"`
+	Data json.RawMessage `json:"data"`
+}
+
+type rawEnvelope struct {
+	Data       rawPayload   `json:"data"`
+	Dst        []string     `json:"dst"`
+	ID         string       `json:"id"`
+	Signatures []SignatureT `json:"signatures,omitempty"`
+	Src        string       `json:"src"`
+	T          float64      `json:"t"`
+	TTL        float64      `json:"ttl"`
+	V          string       `json:"v"`
+}
+
+// RawEnvelope is a decoded envelope which keeps the received bytes and
+// leaves data.data as json.RawMessage until it is accessed. Forwarding
+// Bytes passes the envelope on exactly as it was received.
+type RawEnvelope struct {
+	raw        []byte
+	ID         string
+	Src        string
+	Dst        []string
+	T          float64
+	TTL        float64
+	V          V
+	Kind       string
+	Signatures []SignatureT
+	RawData    json.RawMessage
+	// UseNumber decodes the numbers of the data as json.Number
+	UseNumber bool
+	data      map[string]interface{}
+}
+
+// UnmarshalRawEnvelope decodes the header of data, data is retained and
+// must not be modified afterwards.
+func UnmarshalRawEnvelope(data []byte) (*RawEnvelope, error) {
+	env := rawEnvelope{}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	v, err := FromV(env.V)
+	if err != nil {
+		return nil, err
+	}
+	if env.Dst == nil {
+		return nil, fmt.Errorf("envelope %s without dst", env.ID)
+	}
+	return &RawEnvelope{
+		raw:        data,
+		ID:         env.ID,
+		Src:        env.Src,
+		Dst:        env.Dst,
+		T:          env.T,
+		TTL:        env.TTL,
+		V:          v,
+		Kind:       env.Data.Kind,
+		Signatures: env.Signatures,
+		RawData:    env.Data.Data,
+	}, nil
+}
+
+// Bytes are the bytes the envelope was decoded from.
+func (r *RawEnvelope) Bytes() []byte {
+	return r.raw
+}
+
+func (r *RawEnvelope) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(r.raw)
+	return int64(n), err
+}
+
+// Data parses data.data on the first call.
+func (r *RawEnvelope) Data() (map[string]interface{}, error) {
+	if r.data == nil {
+		data := map[string]interface{}{}
+		dec := json.NewDecoder(bytes.NewReader(r.RawData))
+		if r.UseNumber {
+			dec.UseNumber()
+		}
+		if err := dec.Decode(&data); err != nil {
+			return nil, err
+		}
+		r.data = data
+	}
+	return r.data, nil
+}
+
+// DecodeData parses data.data into v, e.g. a typed struct of the kind.
+func (r *RawEnvelope) DecodeData(v interface{}) error {
+	return json.Unmarshal(r.RawData, v)
+}
+
+// Envelope returns the fully decoded envelope like UnmarshalEnvelopeT.
+func (r *RawEnvelope) Envelope() (*EnvelopeT, error) {
+	data, err := r.Data()
+	if err != nil {
+		return nil, err
+	}
+	return &EnvelopeT{
+		Data:       PayloadT1{Kind: r.Kind, Data: data},
+		Dst:        r.Dst,
+		ID:         r.ID,
+		Signatures: r.Signatures,
+		Src:        r.Src,
+		T:          r.T,
+		TTL:        r.TTL,
+		V:          r.V,
+	}, nil
+}
+
+// VerifyID checks the ID against data.data, see VerifyID.
+func (r *RawEnvelope) VerifyID() error {
+	env, err := r.Envelope()
+	if err != nil {
+		return err
+	}
+	return VerifyID(env)
+}
+
+// IsCanonical reports whether the received bytes are the canonical form
+// of the envelope, which is what NewSimpleEnvelope renders without indent.
+// The signatures are not part of the canonical form.
+func (r *RawEnvelope) IsCanonical() (bool, error) {
+	if len(r.Signatures) > 0 {
+		return false, nil
+	}
+	env, err := r.Envelope()
+	if err != nil {
+		return false, err
+	}
+	return canonicalJson(env) == string(r.raw), nil
+}
@@ -0,0 +1,107 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ogs "github.com/mabels/object-graph-streamer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RawEnvelopeSuite struct {
+	suite.Suite
+}
+
+func (s *RawEnvelopeSuite) envelope(jsonProp *ogs.JsonProps) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Dst: []string{"raw"},
+		Data: PayloadT1{
+			Kind: "sample",
+			Data: map[string]interface{}{"name": "object", "date": "2021-05-20", "n": 1.5},
+		},
+		JsonProp:      jsonProp,
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *RawEnvelopeSuite) TestLazyData() {
+	se := s.envelope(ogs.NewJsonProps(2, ""))
+	raw, err := UnmarshalRawEnvelope([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), *se.AsJson(), string(raw.Bytes()))
+	assert.Equal(s.T(), se.AsEnvelope().ID, raw.ID)
+	assert.Equal(s.T(), "sample", raw.Kind)
+	assert.Equal(s.T(), []string{"raw"}, raw.Dst)
+	assert.Nil(s.T(), raw.data)
+	assert.Contains(s.T(), string(raw.RawData), `"name": "object"`)
+
+	typ := SampleNameDate{}
+	assert.NoError(s.T(), raw.DecodeData(&typ))
+	assert.Equal(s.T(), "object", typ.Name)
+	assert.Nil(s.T(), raw.data)
+
+	assert.NoError(s.T(), raw.VerifyID())
+	env, err := raw.Envelope()
+	assert.NoError(s.T(), err)
+	expected, err := UnmarshalEnvelopeT(raw.Bytes())
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), expected, env)
+
+	out := bytes.Buffer{}
+	_, err = raw.WriteTo(&out)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), *se.AsJson(), out.String())
+}
+
+func (s *RawEnvelopeSuite) TestIsCanonical() {
+	raw, err := UnmarshalRawEnvelope([]byte(*s.envelope(nil).AsJson()))
+	assert.NoError(s.T(), err)
+	ok, err := raw.IsCanonical()
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), ok)
+
+	raw, err = UnmarshalRawEnvelope([]byte(*s.envelope(ogs.NewJsonProps(2, "")).AsJson()))
+	assert.NoError(s.T(), err)
+	ok, err = raw.IsCanonical()
+	assert.NoError(s.T(), err)
+	assert.False(s.T(), ok)
+}
+
+func (s *RawEnvelopeSuite) TestUseNumber() {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "sample", Data: map[string]interface{}{"id": uint64(9007199254740993)}},
+		TimeGenerator: mtimer,
+	})
+	raw, err := UnmarshalRawEnvelope([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+	raw.UseNumber = true
+	data, err := raw.Data()
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), json.Number("9007199254740993"), data["id"])
+	assert.NoError(s.T(), raw.VerifyID())
+}
+
+func (s *RawEnvelopeSuite) TestTampered() {
+	js := bytes.Replace([]byte(*s.envelope(nil).AsJson()), []byte(`"object"`), []byte(`"other"`), 1)
+	raw, err := UnmarshalRawEnvelope(js)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), errors.Is(raw.VerifyID(), ErrIDMismatch))
+}
+
+func (s *RawEnvelopeSuite) TestInvalid() {
+	_, err := UnmarshalRawEnvelope([]byte(`{"data":{"kind":"x","data":{}},"id":"i","src":"s","t":1,"ttl":1,"v":"A"}`))
+	assert.Error(s.T(), err)
+	_, err = UnmarshalRawEnvelope([]byte(`{"data":{"kind":"x","data":{}},"dst":[],"id":"i","src":"s","t":1,"ttl":1,"v":"B"}`))
+	assert.Error(s.T(), err)
+	_, err = UnmarshalRawEnvelope([]byte(`{"data":`))
+	assert.Error(s.T(), err)
+}
+
+func TestRawEnvelopeSuite(t *testing.T) {
+	suite.Run(t, new(RawEnvelopeSuite))
+}
@@ -0,0 +1,199 @@
+package c5
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+	ogs "github.com/mabels/object-graph-streamer"
+)
+
+// RedactedKey is added to the data of a redacted envelope:
+// {"_redacted":{"id":"<original id>","paths":["customer.email",...]}}
+const RedactedKey = "_redacted"
+
+type RedactAction string
+
+const (
+	RedactDrop     RedactAction = "drop"
+	RedactMask     RedactAction = "mask"
+	RedactHash     RedactAction = "hash"
+	RedactTruncate RedactAction = "truncate"
+)
+
+// RedactionRule applies Action to the values at Path of envelopes of Kind.
+// An empty Kind or "*" matches every kind, "*" path segments match every
+// attribute or array element. Keep is the number of visible trailing
+// characters for mask and the length for truncate.
+type RedactionRule struct {
+	Kind   string       `json:"kind,omitempty"`
+	Path   string       `json:"path"`
+	Action RedactAction `json:"action"`
+	Keep   int          `json:"keep,omitempty"`
+}
+
+// RedactionPolicy applies its Rules in order. The hash action is an
+// HMAC-SHA256 keyed with HashKey, so the hashed values can't be found by
+// hashing guesses. Without a HashKey a random key is used for the lifetime
+// of the policy, equal values still hash equal within it.
+type RedactionPolicy struct {
+	Rules   []RedactionRule `json:"rules"`
+	HashKey string          `json:"hashKey,omitempty"`
+
+	keyOnce sync.Once
+	hashKey []byte
+}
+
+func ParseRedactionPolicy(data []byte) (*RedactionPolicy, error) {
+	policy := RedactionPolicy{}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	for _, rule := range policy.Rules {
+		switch rule.Action {
+		case RedactDrop, RedactMask, RedactHash, RedactTruncate:
+		default:
+			return nil, fmt.Errorf("unknown redaction action:%s", rule.Action)
+		}
+	}
+	return &policy, nil
+}
+
+func (r *RedactionRule) matchesKind(kind string) bool {
+	return r.Kind == "" || r.Kind == DataPathWildcard || r.Kind == kind
+}
+
+func maskValue(val interface{}, keep int) string {
+	str, ok := val.(string)
+	if !ok {
+		str = fmt.Sprintf("%v", val)
+	}
+	runes := []rune(str)
+	if keep < 0 || keep > len(runes) {
+		keep = 0
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+func (p *RedactionPolicy) key() []byte {
+	p.keyOnce.Do(func() {
+		if p.HashKey != "" {
+			p.hashKey = []byte(p.HashKey)
+			return
+		}
+		p.hashKey = make([]byte, 32)
+		if _, err := rand.Read(p.hashKey); err != nil {
+			panic(err)
+		}
+	})
+	return p.hashKey
+}
+
+func hashValue(key []byte, val interface{}) string {
+	hashC := newHashCollector()
+	ogs.ObjectGraphStreamer(canonicalizeData(val), func(sval ogs.SVal) {
+		hashC.Append(sval)
+	})
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(hashC.Digest()))
+	return base58.Encode(mac.Sum(nil))
+}
+
+func truncateValue(val interface{}, keep int) interface{} {
+	switch v := val.(type) {
+	case string:
+		runes := []rune(v)
+		if len(runes) > keep {
+			return string(runes[:keep])
+		}
+	case []interface{}:
+		if len(v) > keep {
+			return v[:keep]
+		}
+	}
+	return val
+}
+
+func (r *RedactionRule) apply(data map[string]interface{}, path []string, key []byte) {
+	if r.Action == RedactDrop {
+		deleteDataPath(data, path)
+		return
+	}
+	val, _ := getDataPath(data, path)
+	var ret interface{}
+	switch r.Action {
+	case RedactMask:
+		ret = maskValue(val, r.Keep)
+	case RedactHash:
+		ret = hashValue(key, val)
+	case RedactTruncate:
+		ret = truncateValue(val, r.Keep)
+	}
+	setDataPath(data, path, ret)
+}
+
+// RedactData applies the matching rules to a copy of pay and returns it
+// with the redacted paths.
+func (p *RedactionPolicy) RedactData(pay PayloadT1) (PayloadT1, []string) {
+	ret := copyPayload(pay)
+	redacted := map[string]bool{}
+	for idx := range p.Rules {
+		rule := &p.Rules[idx]
+		if !rule.matchesKind(pay.Kind) {
+			continue
+		}
+		for _, path := range expandDataPath(ret.Data, splitDataPath(rule.Path)) {
+			rule.apply(ret.Data, path, p.key())
+			redacted[strings.Join(path, ".")] = true
+		}
+	}
+	paths := make([]string, 0, len(redacted))
+	for path := range redacted {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return ret, paths
+}
+
+// Redact returns a new envelope with the policy applied to the data of
+// env. The data carries a reference to the original ID and gets a new ID,
+// header fields are kept.
+func (p *RedactionPolicy) Redact(env *EnvelopeT, jsonProp *ogs.JsonProps) *SimpleEnvelope {
+	pay, paths := p.RedactData(env.Data)
+	redactedPaths := make([]interface{}, len(paths))
+	for idx, path := range paths {
+		redactedPaths[idx] = path
+	}
+	pay.Data[RedactedKey] = map[string]interface{}{
+		"id":    env.ID,
+		"paths": redactedPaths,
+	}
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:      env.Src,
+		Dst:      env.Dst,
+		T:        env.T,
+		TTL:      int(env.TTL),
+		Data:     pay,
+		JsonProp: jsonProp,
+	})
+}
+
+func (p *RedactionPolicy) RedactSimpleEnvelope(se *SimpleEnvelope) *SimpleEnvelope {
+	return p.Redact(se.AsEnvelope(), se.simpleEnvelopeProps.JsonProp)
+}
+
+// RedactedFrom returns the original ID of a redacted envelope.
+func RedactedFrom(env *EnvelopeT) (string, bool) {
+	ref, ok := env.Data.Data[RedactedKey].(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	id, ok := ref["id"].(string)
+	return id, ok
+}
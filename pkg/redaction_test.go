@@ -0,0 +1,110 @@
+package c5
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RedactionSuite struct {
+	suite.Suite
+}
+
+func (s *RedactionSuite) envelope(kind string) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Dst: []string{"audit"},
+		Data: PayloadT1{
+			Kind: kind,
+			Data: map[string]interface{}{
+				"customer": map[string]interface{}{
+					"name":  "Meno Abels",
+					"email": "meno@example.com",
+					"ssn":   "123-45-6789",
+					"card":  "4111111111111111",
+				},
+				"items": []interface{}{
+					map[string]interface{}{"sku": "a", "note": "deliver to the back door"},
+					map[string]interface{}{"sku": "b", "note": "fragile"},
+				},
+			},
+		},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *RedactionSuite) policy() *RedactionPolicy {
+	policy, err := ParseRedactionPolicy([]byte(`{"hashKey":"test case","rules":[
+		{"kind":"customer","path":"customer.ssn","action":"drop"},
+		{"path":"customer.card","action":"mask","keep":4},
+		{"kind":"*","path":"customer.email","action":"hash"},
+		{"kind":"customer","path":"items.*.note","action":"truncate","keep":7},
+		{"kind":"other","path":"customer.name","action":"drop"}
+	]}`))
+	assert.NoError(s.T(), err)
+	return policy
+}
+
+func (s *RedactionSuite) TestRedact() {
+	orig := s.envelope("customer")
+	red := s.policy().RedactSimpleEnvelope(orig)
+	env := red.AsEnvelope()
+	customer := env.Data.Data["customer"].(map[string]interface{})
+	assert.NotContains(s.T(), customer, "ssn")
+	assert.Equal(s.T(), "************1111", customer["card"])
+	assert.Equal(s.T(), hashValue([]byte("test case"), "meno@example.com"), customer["email"])
+	assert.Equal(s.T(), "Meno Abels", customer["name"])
+	items := env.Data.Data["items"].([]interface{})
+	assert.Equal(s.T(), "deliver", items[0].(map[string]interface{})["note"])
+	assert.Equal(s.T(), "fragile", items[1].(map[string]interface{})["note"])
+
+	from, ok := RedactedFrom(env)
+	assert.True(s.T(), ok)
+	assert.Equal(s.T(), orig.AsEnvelope().ID, from)
+	assert.Equal(s.T(), []interface{}{
+		"customer.card", "customer.email", "customer.ssn", "items.0.note", "items.1.note",
+	}, env.Data.Data[RedactedKey].(map[string]interface{})["paths"])
+
+	assert.NotEqual(s.T(), orig.AsEnvelope().ID, env.ID)
+	assert.NoError(s.T(), VerifyID(env))
+	assert.Equal(s.T(), orig.AsEnvelope().T, env.T)
+	assert.Equal(s.T(), []string{"audit"}, env.Dst)
+
+	// the original is untouched
+	assert.Equal(s.T(), "123-45-6789", orig.AsEnvelope().Data.Data["customer"].(map[string]interface{})["ssn"])
+}
+
+func (s *RedactionSuite) TestKindFilter() {
+	env := s.policy().RedactSimpleEnvelope(s.envelope("other")).AsEnvelope()
+	customer := env.Data.Data["customer"].(map[string]interface{})
+	assert.NotContains(s.T(), customer, "name")
+	assert.Equal(s.T(), "123-45-6789", customer["ssn"])
+}
+
+func (s *RedactionSuite) TestHashKey() {
+	email := func(policy *RedactionPolicy) interface{} {
+		env := policy.RedactSimpleEnvelope(s.envelope("customer")).AsEnvelope()
+		return env.Data.Data["customer"].(map[string]interface{})["email"]
+	}
+	keyed := email(s.policy())
+	assert.Equal(s.T(), keyed, email(s.policy()))
+	policy := s.policy()
+	policy.HashKey = "other"
+	assert.NotEqual(s.T(), keyed, email(policy))
+
+	random := &RedactionPolicy{Rules: []RedactionRule{{Path: "customer.email", Action: RedactHash}}}
+	hashed := email(random)
+	assert.NotEqual(s.T(), keyed, hashed)
+	assert.Equal(s.T(), hashed, email(random))
+	assert.NotEqual(s.T(), hashed, email(&RedactionPolicy{Rules: random.Rules}))
+}
+
+func (s *RedactionSuite) TestUnknownAction() {
+	_, err := ParseRedactionPolicy([]byte(`{"rules":[{"path":"a","action":"burn"}]}`))
+	assert.Error(s.T(), err)
+}
+
+func TestRedactionSuite(t *testing.T) {
+	suite.Run(t, new(RedactionSuite))
+}
@@ -0,0 +1,257 @@
+package c5
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SelectiveDisclosureKey holds the digests of the disclosable attributes
+// of an object, like "_sd" in SD-JWT.
+const SelectiveDisclosureKey = "_sd"
+
+var (
+	ErrDisclosureInvalid = errors.New("disclosure invalid")
+	ErrDisclosureUnknown = errors.New("disclosure not committed")
+	ErrDisclosurePath    = errors.New("selective disclosure path not found")
+)
+
+// SelectiveDisclosureProps switches NewSimpleEnvelope into a mode where
+// the attributes at Paths are replaced by salted digests, so the ID
+// commits to them without revealing them.
+type SelectiveDisclosureProps struct {
+	Paths         []string
+	SaltGenerator func() string
+}
+
+// Disclosure reveals one attribute. Encoded is base64url of the JSON array
+// [salt, name, value], its SHA-256 is what the envelope carries.
+type Disclosure struct {
+	Path    string
+	Salt    string
+	Name    string
+	Value   interface{}
+	Encoded string
+}
+
+func randomSalt() string {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return b64url.EncodeToString(salt)
+}
+
+func NewDisclosure(path string, salt string, value interface{}) (*Disclosure, error) {
+	dpath := splitDataPath(path)
+	name := dpath[len(dpath)-1]
+	b, err := json.Marshal([]interface{}{salt, name, value})
+	if err != nil {
+		return nil, err
+	}
+	return &Disclosure{
+		Path:    path,
+		Salt:    salt,
+		Name:    name,
+		Value:   value,
+		Encoded: b64url.EncodeToString(b),
+	}, nil
+}
+
+// ParseDisclosure decodes an encoded disclosure; Path is unknown until it
+// was matched against an envelope.
+func ParseDisclosure(encoded string) (*Disclosure, error) {
+	b, err := b64url.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDisclosureInvalid, err)
+	}
+	arr := []interface{}{}
+	if err := json.Unmarshal(b, &arr); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDisclosureInvalid, err)
+	}
+	if len(arr) != 3 {
+		return nil, fmt.Errorf("%w: %d elements", ErrDisclosureInvalid, len(arr))
+	}
+	salt, okSalt := arr[0].(string)
+	name, okName := arr[1].(string)
+	if !okSalt || !okName {
+		return nil, fmt.Errorf("%w: salt and name have to be strings", ErrDisclosureInvalid)
+	}
+	return &Disclosure{Salt: salt, Name: name, Value: arr[2], Encoded: encoded}, nil
+}
+
+func EncodedDisclosures(disclosures []Disclosure) []string {
+	ret := make([]string, len(disclosures))
+	for idx, d := range disclosures {
+		ret[idx] = d.Encoded
+	}
+	return ret
+}
+
+func (d *Disclosure) Digest() string {
+	sum := sha256.Sum256([]byte(d.Encoded))
+	return b64url.EncodeToString(sum[:])
+}
+
+func addDisclosureDigest(obj map[string]interface{}, digest string) {
+	digests, _ := obj[SelectiveDisclosureKey].([]interface{})
+	digests = append(digests, digest)
+	sort.Slice(digests, func(i, j int) bool { return digests[i].(string) < digests[j].(string) })
+	obj[SelectiveDisclosureKey] = digests
+}
+
+// commitDisclosures replaces the attributes at paths of a copy of pay by
+// their digests, deepest paths first so nested disclosures end up inside
+// the value of their parent.
+func commitDisclosures(pay PayloadT1, sdp *SelectiveDisclosureProps) (PayloadT1, []Disclosure, error) {
+	saltGenerator := sdp.SaltGenerator
+	if saltGenerator == nil {
+		saltGenerator = randomSalt
+	}
+	ret := copyPayload(pay)
+	paths := append([]string{}, sdp.Paths...)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(splitDataPath(paths[i])) > len(splitDataPath(paths[j]))
+	})
+	disclosures := make([]Disclosure, 0, len(paths))
+	for _, path := range paths {
+		dpath := splitDataPath(path)
+		parent, found := getDataPath(ret.Data, dpath[:len(dpath)-1])
+		obj, isObj := parent.(map[string]interface{})
+		if !found || !isObj {
+			return pay, nil, fmt.Errorf("%w: %s has no object parent", ErrDisclosurePath, path)
+		}
+		name := dpath[len(dpath)-1]
+		val, found := obj[name]
+		if !found {
+			return pay, nil, fmt.Errorf("%w: %s", ErrDisclosurePath, path)
+		}
+		disclosure, err := NewDisclosure(path, saltGenerator(), val)
+		if err != nil {
+			return pay, nil, err
+		}
+		delete(obj, name)
+		addDisclosureDigest(obj, disclosure.Digest())
+		disclosures = append(disclosures, *disclosure)
+	}
+	return ret, disclosures, nil
+}
+
+// Disclosures of an envelope created with SelectiveDisclosureProps.
+func (s *SimpleEnvelope) Disclosures() []Disclosure {
+	return s.disclosures
+}
+
+// Present is the holder side: the envelope goes out as it is, together
+// with the disclosures of the paths to reveal.
+func Present(env *EnvelopeT, disclosures []Disclosure, reveal ...string) (*EnvelopeT, []Disclosure) {
+	ret := *env
+	ret.Data = copyPayload(env.Data)
+	selected := []Disclosure{}
+	for _, d := range disclosures {
+		for _, path := range reveal {
+			if d.Path == path || strings.HasPrefix(path, d.Path+".") {
+				// revealing a nested attribute needs its parents as well
+				selected = append(selected, d)
+				break
+			}
+		}
+	}
+	return &ret, selected
+}
+
+func findDigest(node interface{}, digest string, path []string) (map[string]interface{}, []string) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if digests, ok := v[SelectiveDisclosureKey].([]interface{}); ok {
+			for _, d := range digests {
+				if d == digest {
+					return v, path
+				}
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if obj, opath := findDigest(v[key], digest, append(path[:len(path):len(path)], key)); obj != nil {
+				return obj, opath
+			}
+		}
+	case []interface{}:
+		for idx, val := range v {
+			if obj, opath := findDigest(val, digest, append(path[:len(path):len(path)], fmt.Sprint(idx))); obj != nil {
+				return obj, opath
+			}
+		}
+	}
+	return nil, nil
+}
+
+func removeDisclosureDigests(node interface{}) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		delete(v, SelectiveDisclosureKey)
+		for _, val := range v {
+			removeDisclosureDigests(val)
+		}
+	case []interface{}:
+		for _, val := range v {
+			removeDisclosureDigests(val)
+		}
+	}
+}
+
+// VerifyDisclosures is the verifier side: the ID of env has to match its
+// (committed) data and every disclosure has to be committed in it. It
+// returns the data with the disclosed attributes filled in and all
+// undisclosed ones removed.
+func VerifyDisclosures(env *EnvelopeT, encoded []string) (map[string]interface{}, []Disclosure, error) {
+	if err := VerifyID(env); err != nil {
+		return nil, nil, err
+	}
+	data := copyData(env.Data.Data).(map[string]interface{})
+	pending := make([]*Disclosure, 0, len(encoded))
+	seen := map[string]bool{}
+	for _, enc := range encoded {
+		d, err := ParseDisclosure(enc)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen[d.Digest()] {
+			return nil, nil, fmt.Errorf("%w: duplicate disclosure %s", ErrDisclosureInvalid, d.Name)
+		}
+		seen[d.Digest()] = true
+		pending = append(pending, d)
+	}
+	disclosed := []Disclosure{}
+	// a nested disclosure is only found once its parent was disclosed
+	for len(pending) > 0 {
+		rest := pending[:0]
+		for _, d := range pending {
+			obj, path := findDigest(data, d.Digest(), []string{})
+			if obj == nil {
+				rest = append(rest, d)
+				continue
+			}
+			if _, exists := obj[d.Name]; exists {
+				return nil, nil, fmt.Errorf("%w: %s disclosed twice", ErrDisclosureInvalid, d.Name)
+			}
+			obj[d.Name] = d.Value
+			d.Path = strings.Join(append(path, d.Name), ".")
+			disclosed = append(disclosed, *d)
+		}
+		if len(rest) == len(pending) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrDisclosureUnknown, rest[0].Name)
+		}
+		pending = rest
+	}
+	removeDisclosureDigests(data)
+	return data, disclosed, nil
+}
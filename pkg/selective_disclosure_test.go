@@ -0,0 +1,129 @@
+package c5
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SelectiveDisclosureSuite struct {
+	suite.Suite
+}
+
+func (s *SelectiveDisclosureSuite) envelope(salt func() string) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "customer",
+			Data: map[string]interface{}{
+				"order": "4711",
+				"customer": map[string]interface{}{
+					"name":  "Meno",
+					"email": "meno@example.com",
+					"ssn":   "123-45-6789",
+				},
+			},
+		},
+		SelectiveDisclosure: &SelectiveDisclosureProps{
+			Paths:         []string{"customer", "customer.email", "customer.ssn"},
+			SaltGenerator: salt,
+		},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *SelectiveDisclosureSuite) TestCommittedData() {
+	se := s.envelope(nil)
+	json := *se.AsJson()
+	assert.NotContains(s.T(), json, "Meno")
+	assert.NotContains(s.T(), json, "meno@example.com")
+	assert.Contains(s.T(), json, `"order":"4711"`)
+	assert.Len(s.T(), se.Disclosures(), 3)
+	assert.NoError(s.T(), VerifyID(se.AsEnvelope()))
+}
+
+func (s *SelectiveDisclosureSuite) TestDeterministicWithSalt() {
+	cnt := 0
+	salt := func() string { cnt++; return fmt.Sprintf("salt%d", cnt) }
+	a := s.envelope(salt).AsEnvelope().ID
+	cnt = 0
+	b := s.envelope(salt).AsEnvelope().ID
+	assert.Equal(s.T(), a, b)
+}
+
+func (s *SelectiveDisclosureSuite) TestPresentAndVerify() {
+	se := s.envelope(nil)
+	ref, err := UnmarshalEnvelopeT([]byte(*se.AsJson()))
+	assert.NoError(s.T(), err)
+
+	env, disclosures := Present(ref, se.Disclosures(), "customer.email")
+	assert.Equal(s.T(), ref.ID, env.ID)
+	assert.Len(s.T(), disclosures, 2)
+
+	data, disclosed, err := VerifyDisclosures(env, EncodedDisclosures(disclosures))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), disclosed, 2)
+	assert.Equal(s.T(), map[string]interface{}{
+		"order": "4711",
+		"customer": map[string]interface{}{
+			"name":  "Meno",
+			"email": "meno@example.com",
+		},
+	}, data)
+
+	data, _, err = VerifyDisclosures(env, EncodedDisclosures(se.Disclosures()))
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), "123-45-6789", data["customer"].(map[string]interface{})["ssn"])
+	assert.Equal(s.T(), "Meno", data["customer"].(map[string]interface{})["name"])
+}
+
+func (s *SelectiveDisclosureSuite) TestNestedWithoutParentFails() {
+	se := s.envelope(nil)
+	var email Disclosure
+	for _, d := range se.Disclosures() {
+		if d.Path == "customer.email" {
+			email = d
+		}
+	}
+	_, _, err := VerifyDisclosures(se.AsEnvelope(), []string{email.Encoded})
+	assert.True(s.T(), errors.Is(err, ErrDisclosureUnknown))
+}
+
+func (s *SelectiveDisclosureSuite) TestForgedDisclosure() {
+	se := s.envelope(nil)
+	forged, err := NewDisclosure("customer", se.Disclosures()[2].Salt, map[string]interface{}{"name": "Eve"})
+	assert.NoError(s.T(), err)
+	_, _, err = VerifyDisclosures(se.AsEnvelope(), []string{forged.Encoded})
+	assert.True(s.T(), errors.Is(err, ErrDisclosureUnknown))
+
+	env := *se.AsEnvelope()
+	env.Data = copyPayload(env.Data)
+	env.Data.Data["order"] = "4712"
+	_, _, err = VerifyDisclosures(&env, nil)
+	assert.True(s.T(), errors.Is(err, ErrIDMismatch))
+}
+
+func (s *SelectiveDisclosureSuite) TestUnknownPath() {
+	props := &SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "customer",
+			Data: map[string]interface{}{"order": "4711", "customer": map[string]interface{}{"name": "x"}},
+		},
+	}
+	for _, path := range []string{"customer.email", "order.number", "nothing.here"} {
+		props.SelectiveDisclosure = &SelectiveDisclosureProps{Paths: []string{path}}
+		_, err := BuildSimpleEnvelope(props)
+		assert.True(s.T(), errors.Is(err, ErrDisclosurePath), path)
+	}
+	assert.Panics(s.T(), func() {
+		NewSimpleEnvelope(props)
+	})
+}
+
+func TestSelectiveDisclosureSuite(t *testing.T) {
+	suite.Run(t, new(SelectiveDisclosureSuite))
+}
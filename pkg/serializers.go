@@ -0,0 +1,59 @@
+package c5
+
+import (
+	"reflect"
+	"sync"
+)
+
+// ValueSerializer returns the canonical representation of v, e.g. the
+// string of a decimal or the name of an enum. The result is mapped again
+// like any other value of the data.
+type ValueSerializer func(v interface{}) interface{}
+
+// Serializers maps Go types to their ValueSerializer. They are used for
+// the JSON and the hash of the data, so every party which creates or
+// verifies envelopes with such types has to register the same serializers.
+//
+// The plain JSON types string, bool, float64, int, int64, json.Number,
+// map[string]interface{} and []interface{} are never serialized.
+type Serializers struct {
+	lock  sync.RWMutex
+	types map[reflect.Type]ValueSerializer
+}
+
+// DefaultSerializers are used by every envelope after the Serializers of
+// its SimpleEnvelopeProps.
+var DefaultSerializers = NewSerializers()
+
+func NewSerializers() *Serializers {
+	return &Serializers{
+		types: map[reflect.Type]ValueSerializer{},
+	}
+}
+
+// Register sets fn as serializer of the type of sample, a pointer type
+// is registered by passing a (nil) pointer.
+func (s *Serializers) Register(sample interface{}, fn ValueSerializer) *Serializers {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.types[reflect.TypeOf(sample)] = fn
+	return s
+}
+
+func (s *Serializers) Unregister(sample interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.types, reflect.TypeOf(sample))
+}
+
+func (s *Serializers) lookup(typ reflect.Type) (ValueSerializer, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	fn, ok := s.types[typ]
+	return fn, ok
+}
+
+// RegisterSerializer registers fn in the DefaultSerializers.
+func RegisterSerializer(sample interface{}, fn ValueSerializer) {
+	DefaultSerializers.Register(sample, fn)
+}
@@ -0,0 +1,113 @@
+package c5
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SerializersSuite struct {
+	suite.Suite
+}
+
+type testDecimal struct {
+	unscaled int64
+	scale    int
+}
+
+func (d testDecimal) String() string {
+	str := fmt.Sprintf("%0*d", d.scale+1, d.unscaled)
+	return str[:len(str)-d.scale] + "." + str[len(str)-d.scale:]
+}
+
+type testUUID [4]byte
+
+type testColor int
+
+const (
+	red testColor = iota
+	green
+)
+
+func (s *SerializersSuite) envelope(data map[string]interface{}, serializers *Serializers) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "test", Data: data},
+		TimeGenerator: mtimer,
+		Serializers:   serializers,
+	})
+}
+
+func (s *SerializersSuite) TestEnvelopeSerializers() {
+	serializers := NewSerializers().
+		Register(testDecimal{}, func(v interface{}) interface{} {
+			return v.(testDecimal).String()
+		}).
+		Register(testUUID{}, func(v interface{}) interface{} {
+			return fmt.Sprintf("%x", v.(testUUID))
+		}).
+		Register(red, func(v interface{}) interface{} {
+			return []string{"red", "green"}[v.(testColor)]
+		})
+	amount := testDecimal{unscaled: 1250, scale: 2}
+	se := s.envelope(map[string]interface{}{
+		"amount": amount,
+		"ptr":    &amount,
+		"id":     testUUID{0xde, 0xad, 0xbe, 0xef},
+		"colors": []testColor{red, green},
+	}, serializers)
+	plain := s.envelope(map[string]interface{}{
+		"amount": "12.50",
+		"ptr":    "12.50",
+		"id":     "deadbeef",
+		"colors": []interface{}{"red", "green"},
+	}, nil)
+	assert.Equal(s.T(), plain.AsEnvelope().ID, se.AsEnvelope().ID)
+	assert.Equal(s.T(), *plain.AsJson(), *se.AsJson())
+	assert.Equal(s.T(), *plain.AsJson(), string(se.AppendJson(nil)))
+
+	// without serializers the types are mapped like encoding/json
+	unmapped := s.envelope(se.AsEnvelope().Data.Data, nil)
+	assert.Contains(s.T(), *unmapped.AsJson(), `"amount":{}`)
+	assert.Contains(s.T(), *unmapped.AsJson(), `"colors":[0,1]`)
+	assert.Equal(s.T(), *unmapped.AsJson(), string(unmapped.AppendJson(nil)))
+}
+
+func (s *SerializersSuite) TestDefaultSerializers() {
+	RegisterSerializer(testDecimal{}, func(v interface{}) interface{} {
+		return v.(testDecimal).String()
+	})
+	defer DefaultSerializers.Unregister(testDecimal{})
+	data := map[string]interface{}{"amount": testDecimal{unscaled: 5, scale: 3}}
+	se := s.envelope(data, nil)
+	assert.Contains(s.T(), *se.AsJson(), `"amount":"0.005"`)
+	assert.Equal(s.T(), *se.AsJson(), string(se.AppendJson(nil)))
+
+	// the serializers of the envelope come first
+	local := NewSerializers().Register(testDecimal{}, func(v interface{}) interface{} {
+		return strings.TrimRight(v.(testDecimal).String(), "0")
+	})
+	data = map[string]interface{}{"amount": testDecimal{unscaled: 50, scale: 2}}
+	assert.Contains(s.T(), *s.envelope(data, local).AsJson(), `"amount":"0.5"`)
+	assert.Contains(s.T(), *s.envelope(data, nil).AsJson(), `"amount":"0.50"`)
+}
+
+func (s *SerializersSuite) TestSameTypeResult() {
+	// the result is not passed to the serializer of its type again
+	serializers := NewSerializers().Register(red, func(v interface{}) interface{} {
+		if v.(testColor) > green {
+			return green
+		}
+		return v
+	})
+	se := s.envelope(map[string]interface{}{"color": testColor(7)}, serializers)
+	assert.Contains(s.T(), *se.AsJson(), `"color":1`)
+	assert.Equal(s.T(), *se.AsJson(), string(se.AppendJson(nil)))
+}
+
+func TestSerializersSuite(t *testing.T) {
+	suite.Run(t, new(SerializersSuite))
+}
@@ -0,0 +1,374 @@
+package c5
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+var (
+	ErrSignatureInvalid   = errors.New("signature invalid")
+	ErrNoSignatures       = errors.New("no signatures")
+	ErrPolicyNotSatisfied = errors.New("signature policy not satisfied")
+	ErrHeaderInvalid      = errors.New("envelope header invalid")
+)
+
+func hashForAlg(alg string) (crypto.Hash, error) {
+	switch alg {
+	case AlgEdDSA:
+		return crypto.Hash(0), nil
+	case AlgES256, AlgRS256:
+		return crypto.SHA256, nil
+	case AlgES384:
+		return crypto.SHA384, nil
+	}
+	return 0, fmt.Errorf("%w: alg %q", ErrUnsupportedKey, alg)
+}
+
+func digest(h crypto.Hash, msg []byte) []byte {
+	switch h {
+	case crypto.SHA256:
+		sum := sha256.Sum256(msg)
+		return sum[:]
+	case crypto.SHA384:
+		sum := sha512.Sum384(msg)
+		return sum[:]
+	}
+	return msg
+}
+
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// SignBytes signs msg like JWS does: Ed25519 over the message, ECDSA as
+// fixed size r||s and RSA as PKCS#1 v1.5.
+func (k *Key) SignBytes(msg []byte) ([]byte, error) {
+	if k.Private == nil {
+		return nil, fmt.Errorf("%w: %s has no private part", ErrNoSigningKey, k.ID)
+	}
+	h, err := hashForAlg(k.Alg)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := k.Private.Sign(rand.Reader, digest(h, msg), h)
+	if err != nil {
+		return nil, err
+	}
+	if pub, ok := k.Public.(*ecdsa.PublicKey); ok {
+		es := ecdsaSignature{}
+		if _, err := asn1.Unmarshal(sig, &es); err != nil {
+			return nil, err
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		raw := make([]byte, 2*size)
+		es.R.FillBytes(raw[:size])
+		es.S.FillBytes(raw[size:])
+		return raw, nil
+	}
+	return sig, nil
+}
+
+// VerifyBytes is the counterpart of SignBytes.
+func (k *Key) VerifyBytes(msg []byte, sig []byte) error {
+	h, err := hashForAlg(k.Alg)
+	if err != nil {
+		return err
+	}
+	ok := false
+	switch pub := k.Public.(type) {
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, msg, sig)
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) == 2*size {
+			r := new(big.Int).SetBytes(sig[:size])
+			s := new(big.Int).SetBytes(sig[size:])
+			ok = ecdsa.Verify(pub, digest(h, msg), r, s)
+		}
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, h, digest(h, msg), sig) == nil
+	default:
+		return fmt.Errorf("%w: %T", ErrUnsupportedKey, k.Public)
+	}
+	if !ok {
+		return fmt.Errorf("%w: key %s", ErrSignatureInvalid, k.ID)
+	}
+	return nil
+}
+
+// signingInput is what a signature covers: the canonical envelope JSON,
+// a newline and the JSON of the signature with an empty sig. A
+// countersignature adds another newline and the JSON array of all
+// signatures in front of it.
+func signingInput(canonical string, sig *SignatureT, prev []SignatureT) ([]byte, error) {
+	header := *sig
+	header.Sig = ""
+	hb, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(canonical)
+	buf.WriteByte('\n')
+	buf.Write(hb)
+	if sig.Counter {
+		if len(prev) == 0 {
+			return nil, fmt.Errorf("%w: to countersign", ErrNoSignatures)
+		}
+		pb, err := json.Marshal(prev)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+		buf.Write(pb)
+	}
+	return buf.Bytes(), nil
+}
+
+func canonicalJson(env *EnvelopeT) string {
+	return *NewSimpleEnvelopeFromEnvelopeT(env, nil).AsJson()
+}
+
+// checkHeader rejects the headers canonicalJson can't render as received,
+// a signature would cover other values than the ones on the wire.
+func checkHeader(env *EnvelopeT) error {
+	if env.T != math.Trunc(env.T) {
+		return fmt.Errorf("%w: t %v is no integer", ErrHeaderInvalid, env.T)
+	}
+	if env.TTL != math.Trunc(env.TTL) {
+		return fmt.Errorf("%w: ttl %v is no integer", ErrHeaderInvalid, env.TTL)
+	}
+	return nil
+}
+
+type Signer struct {
+	Key           *Key
+	TimeGenerator TimeGenerator
+	// Chain is set for certificate bound keys, see NewCertSigner
+	Chain      []*x509.Certificate
+	EmbedChain bool
+}
+
+func NewSigner(key *Key) *Signer {
+	return &Signer{
+		Key:           key,
+		TimeGenerator: &realTimer{},
+	}
+}
+
+func (s *Signer) signature(canonical string, counter bool, prev []SignatureT) (*SignatureT, error) {
+	now := s.TimeGenerator.Now()
+	if err := s.Key.ValidAt(now); err != nil {
+		return nil, err
+	}
+	sig := &SignatureT{
+		Alg:     s.Key.Alg,
+		Counter: counter,
+		Kid:     s.Key.ID,
+		Src:     s.Key.Src,
+		T:       float64(now.UnixMilli()),
+	}
+	if len(s.Chain) > 0 {
+		sig.X5TS256 = CertFingerprint(s.Chain[0])
+		if s.EmbedChain {
+			sig.X5C = make([]string, len(s.Chain))
+			for idx, cert := range s.Chain {
+				sig.X5C[idx] = base64.StdEncoding.EncodeToString(cert.Raw)
+			}
+		}
+	}
+	input, err := signingInput(canonical, sig, prev)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := s.Key.SignBytes(input)
+	if err != nil {
+		return nil, err
+	}
+	sig.Sig = b64url.EncodeToString(raw)
+	return sig, nil
+}
+
+func (s *Signer) appendSignature(env *EnvelopeT, counter bool) (*EnvelopeT, error) {
+	if err := checkHeader(env); err != nil {
+		return nil, err
+	}
+	sig, err := s.signature(canonicalJson(env), counter, env.Signatures)
+	if err != nil {
+		return nil, err
+	}
+	ret := *env
+	ret.Signatures = append(append([]SignatureT{}, env.Signatures...), *sig)
+	return &ret, nil
+}
+
+// Sign returns a copy of env with a signature over its canonical form
+// appended.
+func (s *Signer) Sign(env *EnvelopeT) (*EnvelopeT, error) {
+	return s.appendSignature(env, false)
+}
+
+// Countersign returns a copy of env with a signature appended which covers
+// the canonical form and every signature before it.
+func (s *Signer) Countersign(env *EnvelopeT) (*EnvelopeT, error) {
+	return s.appendSignature(env, true)
+}
+
+func (s *Signer) SignSimpleEnvelope(se *SimpleEnvelope) (*EnvelopeT, error) {
+	return s.Sign(se.AsEnvelope())
+}
+
+// SignaturePolicy requires Threshold valid signatures of distinct keys.
+// If Kids is set only those keys are counted. A zero Threshold means 1.
+type SignaturePolicy struct {
+	Threshold int
+	Kids      []string
+}
+
+type SignatureResult struct {
+	Index     int
+	Signature SignatureT
+	Key       *Key
+	// Chain is the verified certificate chain of X.509 signatures
+	Chain []*x509.Certificate
+	Err   error
+}
+
+func (r *SignatureResult) Valid() bool {
+	return r.Err == nil
+}
+
+// Verifier checks signatures against the keys of a Keyring. The validity
+// window of a key is checked at the t of the signature, so signatures
+// outlive a rotation. That t is chosen by the signer, so a revoked key is
+// compromised at any t: revocation is checked at the verification time of
+// TimeGenerator unless RevocationAtSignatureTime trusts the signer's t.
+type Verifier struct {
+	Keyring                   *Keyring
+	Policy                    *SignaturePolicy
+	TimeGenerator             TimeGenerator
+	RevocationAtSignatureTime bool
+}
+
+// EnvelopeVerifier is a Verifier or a CertVerifier.
+type EnvelopeVerifier interface {
+	Verify(env *EnvelopeT) ([]SignatureResult, error)
+}
+
+func NewVerifier(kr *Keyring, policy *SignaturePolicy) *Verifier {
+	if policy == nil {
+		policy = &SignaturePolicy{}
+	}
+	return &Verifier{
+		Keyring:       kr,
+		Policy:        policy,
+		TimeGenerator: &realTimer{},
+	}
+}
+
+func checkSignature(key *Key, canonical string, sig *SignatureT, prev []SignatureT) error {
+	if key.Alg != sig.Alg {
+		return fmt.Errorf("%w: alg %q does not match key %s", ErrSignatureInvalid, sig.Alg, key.ID)
+	}
+	raw, err := b64url.DecodeString(sig.Sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignatureInvalid, err)
+	}
+	input, err := signingInput(canonical, sig, prev)
+	if err != nil {
+		return err
+	}
+	return key.VerifyBytes(input, raw)
+}
+
+func (v *Verifier) verifySignature(canonical string, sig *SignatureT, prev []SignatureT) (*Key, error) {
+	key, err := v.Keyring.Lookup(sig.Src, sig.Kid, time.UnixMilli(int64(sig.T)))
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil && !v.RevocationAtSignatureTime {
+		now := time.Now()
+		if v.TimeGenerator != nil {
+			now = v.TimeGenerator.Now()
+		}
+		if !now.Before(*key.RevokedAt) {
+			return nil, fmt.Errorf("%w: %s at %s", ErrKeyRevoked, key.ID, key.RevokedAt.Format(JSISOStringMillisFormat))
+		}
+	}
+	return key, checkSignature(key, canonical, sig, prev)
+}
+
+// Verify checks every signature of env and then the policy. The per
+// signature results are returned in any case. A signature made by a known
+// key which doesn't verify fails the envelope even if the policy is met.
+func (v *Verifier) Verify(env *EnvelopeT) ([]SignatureResult, error) {
+	return verifyAll(env, v.Policy, func(canonical string, idx int) SignatureResult {
+		sig := env.Signatures[idx]
+		key, err := v.verifySignature(canonical, &sig, env.Signatures[:idx])
+		return SignatureResult{Index: idx, Signature: sig, Key: key, Err: err}
+	})
+}
+
+func verifyAll(env *EnvelopeT, policy *SignaturePolicy, verify func(canonical string, idx int) SignatureResult) ([]SignatureResult, error) {
+	if len(env.Signatures) == 0 {
+		return nil, ErrNoSignatures
+	}
+	if err := checkHeader(env); err != nil {
+		return nil, err
+	}
+	canonical := canonicalJson(env)
+	results := make([]SignatureResult, len(env.Signatures))
+	var tampered error
+	for idx := range env.Signatures {
+		results[idx] = verify(canonical, idx)
+		err := results[idx].Err
+		if tampered == nil && errors.Is(err, ErrSignatureInvalid) {
+			tampered = fmt.Errorf("signature %d: %w", idx, err)
+		}
+	}
+	if tampered != nil {
+		return results, tampered
+	}
+	return results, policy.check(results)
+}
+
+func (p *SignaturePolicy) check(results []SignatureResult) error {
+	threshold := p.Threshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	allowed := map[string]bool{}
+	for _, kid := range p.Kids {
+		allowed[kid] = true
+	}
+	valid := map[string]bool{}
+	for idx := range results {
+		res := &results[idx]
+		if !res.Valid() {
+			continue
+		}
+		if len(allowed) > 0 && !allowed[res.Key.ID] {
+			continue
+		}
+		valid[res.Key.ID] = true
+	}
+	if len(valid) < threshold {
+		return fmt.Errorf("%w: %d of %d valid signatures", ErrPolicyNotSatisfied, len(valid), threshold)
+	}
+	return nil
+}
@@ -0,0 +1,204 @@
+package c5
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignatureSuite struct {
+	suite.Suite
+	keyring *Keyring
+	keys    map[string]*Key
+}
+
+func (s *SignatureSuite) SetupTest() {
+	s.keyring = NewKeyring(nil)
+	s.keys = map[string]*Key{}
+	for name, alg := range map[string]string{
+		"originator": AlgEdDSA,
+		"gateway":    AlgES256,
+		"auditor":    AlgRS256,
+	} {
+		key, err := GenerateKey(alg, name)
+		assert.NoError(s.T(), err)
+		assert.NoError(s.T(), s.keyring.Add(key))
+		s.keys[name] = key
+	}
+}
+
+func (s *SignatureSuite) signer(name string) *Signer {
+	signer := NewSigner(s.keys[name])
+	signer.TimeGenerator = mtimer
+	return signer
+}
+
+func (s *SignatureSuite) envelope() *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "originator",
+		Data: PayloadT1{
+			Kind: "kind",
+			Data: map[string]interface{}{"y": 4},
+		},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *SignatureSuite) TestSignAndVerify() {
+	env, err := s.signer("originator").SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), env.Signatures, 1)
+	assert.Equal(s.T(), s.keys["originator"].ID, env.Signatures[0].Kid)
+
+	results, err := NewVerifier(s.keyring, nil).Verify(env)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), results[0].Valid())
+}
+
+func (s *SignatureSuite) TestHeaderAsReceived() {
+	received := *s.envelope().AsEnvelope()
+	received.TTL = 0
+	env, err := s.signer("originator").Sign(&received)
+	assert.NoError(s.T(), err)
+	assert.Contains(s.T(), canonicalJson(env), `"ttl":0`)
+	verifier := NewVerifier(s.keyring, nil)
+	_, err = verifier.Verify(env)
+	assert.NoError(s.T(), err)
+	env.TTL = 10
+	_, err = verifier.Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrSignatureInvalid))
+
+	env.TTL = 2.5
+	_, err = verifier.Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrHeaderInvalid))
+	_, err = s.signer("originator").Sign(env)
+	assert.True(s.T(), errors.Is(err, ErrHeaderInvalid))
+	env.TTL = 10
+	env.T += 0.5
+	_, err = verifier.Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrHeaderInvalid))
+}
+
+func (s *SignatureSuite) TestSignedEnvelopeJsonRoundTrip() {
+	env, err := s.signer("originator").SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	env, err = s.signer("gateway").Countersign(env)
+	assert.NoError(s.T(), err)
+	b, err := env.Marshal()
+	assert.NoError(s.T(), err)
+	ref, err := UnmarshalEnvelopeT(b)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), env.Signatures, ref.Signatures)
+	_, err = NewVerifier(s.keyring, &SignaturePolicy{Threshold: 2}).Verify(ref)
+	assert.NoError(s.T(), err)
+}
+
+func (s *SignatureSuite) TestCanonicalFormHasNoSignatures() {
+	se := s.envelope()
+	env, err := s.signer("originator").SignSimpleEnvelope(se)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), *se.AsJson(), *NewSimpleEnvelopeFromEnvelopeT(env, nil).AsJson())
+	assert.NotContains(s.T(), *se.AsJson(), "signatures")
+}
+
+func (s *SignatureSuite) TestTamperedData() {
+	env, err := s.signer("originator").SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	env.Data.Data = map[string]interface{}{"y": 5}
+	_, err = NewVerifier(s.keyring, nil).Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrSignatureInvalid))
+}
+
+func (s *SignatureSuite) TestCountersignature() {
+	env, err := s.signer("originator").SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	env, err = s.signer("gateway").Countersign(env)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), env.Signatures[1].Counter)
+
+	_, err = NewVerifier(s.keyring, &SignaturePolicy{Threshold: 2}).Verify(env)
+	assert.NoError(s.T(), err)
+
+	// dropping the first signature breaks the countersignature
+	dropped := *env
+	dropped.Signatures = env.Signatures[1:]
+	results, err := NewVerifier(s.keyring, nil).Verify(&dropped)
+	assert.Error(s.T(), err)
+	assert.False(s.T(), results[0].Valid())
+}
+
+func (s *SignatureSuite) TestCountersignNeedsSignature() {
+	_, err := s.signer("gateway").Countersign(s.envelope().AsEnvelope())
+	assert.True(s.T(), errors.Is(err, ErrNoSignatures))
+}
+
+func (s *SignatureSuite) TestThresholdPolicy() {
+	env, err := s.signer("originator").SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	policy := &SignaturePolicy{
+		Threshold: 2,
+		Kids: []string{
+			s.keys["originator"].ID,
+			s.keys["gateway"].ID,
+			s.keys["auditor"].ID,
+		},
+	}
+	_, err = NewVerifier(s.keyring, policy).Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrPolicyNotSatisfied))
+
+	// the same key twice doesn't count twice
+	twice, err := s.signer("originator").Sign(env)
+	assert.NoError(s.T(), err)
+	_, err = NewVerifier(s.keyring, policy).Verify(twice)
+	assert.True(s.T(), errors.Is(err, ErrPolicyNotSatisfied))
+
+	env, err = s.signer("auditor").Sign(env)
+	assert.NoError(s.T(), err)
+	_, err = NewVerifier(s.keyring, policy).Verify(env)
+	assert.NoError(s.T(), err)
+
+	policy.Kids = policy.Kids[:2]
+	_, err = NewVerifier(s.keyring, policy).Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrPolicyNotSatisfied))
+}
+
+func (s *SignatureSuite) TestUnknownKeyIsNotCounted() {
+	stranger, _ := GenerateKey(AlgEdDSA, "stranger")
+	signer := NewSigner(stranger)
+	env, err := signer.SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	results, err := NewVerifier(s.keyring, nil).Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrPolicyNotSatisfied))
+	assert.True(s.T(), errors.Is(results[0].Err, ErrKeyNotFound))
+}
+
+func (s *SignatureSuite) TestRevokedKeyBackdated() {
+	env, err := s.signer("originator").SignSimpleEnvelope(s.envelope())
+	assert.NoError(s.T(), err)
+	// revoked after the t of the signature, which the signer could have
+	// backdated
+	revokedAt := mtimer.Now().Add(time.Minute)
+	assert.NoError(s.T(), s.keyring.Revoke(s.keys["originator"].ID, revokedAt))
+
+	verifier := NewVerifier(s.keyring, nil)
+	results, err := verifier.Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrPolicyNotSatisfied))
+	assert.True(s.T(), errors.Is(results[0].Err, ErrKeyRevoked))
+
+	verifier.RevocationAtSignatureTime = true
+	_, err = verifier.Verify(env)
+	assert.NoError(s.T(), err)
+
+	// not revoked yet at verification time
+	verifier = NewVerifier(s.keyring, nil)
+	verifier.TimeGenerator = mtimer
+	_, err = verifier.Verify(env)
+	assert.NoError(s.T(), err)
+}
+
+func TestSignatureSuite(t *testing.T) {
+	suite.Run(t, new(SignatureSuite))
+}
@@ -1,6 +1,7 @@
 package c5
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -10,6 +11,13 @@ import (
 
 const JSISOStringFormat = "2006-01-02T15:04:05.999Z07:00"
 
+// JSISOStringMillisFormat is Date.toISOString of JavaScript for UTC times,
+// it always has three fractional digits. Time values in the data are
+// rendered with it.
+const JSISOStringMillisFormat = "2006-01-02T15:04:05.000Z07:00"
+
+var ErrIDMismatch = errors.New("id does not match data hash")
+
 type GeneratorProps struct {
 	SimpleEnvelopeProps *SimpleEnvelopeInternal
 	Hash                *string
@@ -28,6 +36,13 @@ type SimpleEnvelopeProps struct {
 	JsonProp      *ogs.JsonProps
 	TimeGenerator TimeGenerator
 	IdGenerator   IdGeneratorFn
+	// SelectiveDisclosure makes the data hash commit to salted digests of
+	// the selected attributes instead of their values
+	SelectiveDisclosure *SelectiveDisclosureProps
+	// Attachments are listed under AttachmentsKey in the data, see Attach
+	Attachments []Attachment
+	// Serializers map types of the data before the DefaultSerializers
+	Serializers *Serializers
 }
 
 type SimpleEnvelopeInternal struct {
@@ -39,6 +54,11 @@ type SimpleEnvelopeInternal struct {
 	Data        PayloadT1
 	JsonProp    *ogs.JsonProps
 	IdGenerator IdGeneratorFn
+	Serializers *Serializers
+	// receivedTTL keeps a TTL of 0 instead of defaulting it to 10
+	receivedTTL bool
+	// canonical is Data.Data after the type mapping
+	canonical interface{}
 }
 
 type JsonHash struct {
@@ -69,11 +89,22 @@ type SimpleEnvelope struct {
 	envJsonStrings      []string
 	envJsonString       *string
 	envJsonC            *ogs.JsonCollector
+	disclosures         []Disclosure
 	Envelope            *EnvelopeT
 	DataJsonHash        *JsonHash
 }
 
+// NewSimpleEnvelope panics if the props can't be used, BuildSimpleEnvelope
+// returns the error instead.
 func NewSimpleEnvelope(env *SimpleEnvelopeProps) *SimpleEnvelope {
+	se, err := BuildSimpleEnvelope(env)
+	if err != nil {
+		panic(err.Error())
+	}
+	return se
+}
+
+func BuildSimpleEnvelope(env *SimpleEnvelopeProps) (*SimpleEnvelope, error) {
 	var tstmp int64
 	if env.TimeGenerator == nil {
 		env.TimeGenerator = &realTimer{}
@@ -91,7 +122,7 @@ func NewSimpleEnvelope(env *SimpleEnvelopeProps) *SimpleEnvelope {
 	case nil:
 		tstmp = env.TimeGenerator.Now().UnixMilli()
 	default:
-		panic(fmt.Sprintf("unhandled Type:%t", v))
+		return nil, fmt.Errorf("unhandled Type:%t", v)
 	}
 
 	payt := PayloadT1{}
@@ -103,7 +134,18 @@ func NewSimpleEnvelope(env *SimpleEnvelopeProps) *SimpleEnvelope {
 	case PayloadT:
 		payt = PayloadT1(v)
 	default:
-		panic("unhandled Type")
+		return nil, fmt.Errorf("unhandled Type")
+	}
+	if len(env.Attachments) > 0 {
+		payt = withAttachments(payt, env.Attachments)
+	}
+	var disclosures []Disclosure
+	if env.SelectiveDisclosure != nil {
+		var err error
+		payt, disclosures, err = commitDisclosures(payt, env.SelectiveDisclosure)
+		if err != nil {
+			return nil, err
+		}
 	}
 	idGenerator := env.IdGenerator
 	if idGenerator == nil {
@@ -118,52 +160,104 @@ func NewSimpleEnvelope(env *SimpleEnvelopeProps) *SimpleEnvelope {
 		Data:        payt,
 		JsonProp:    env.JsonProp,
 		IdGenerator: idGenerator,
+		Serializers: env.Serializers,
+	}
+	mapper := typeMapper{serializers: env.Serializers}
+	sei.canonical = mapper.data(payt.Data)
+	if mapper.err != nil {
+		return nil, mapper.err
 	}
 	se := &SimpleEnvelope{
 		simpleEnvelopeProps: &sei,
+		disclosures:         disclosures,
 		// envJsonStrings:      make([]string, 1000),
 	}
 	se.envJsonC = ogs.NewJsonCollector(func(part string) {
 		se.envJsonStrings = append(se.envJsonStrings, part)
 	}, se.simpleEnvelopeProps.JsonProp)
+	return se, nil
+}
+
+// NewSimpleEnvelopeFromEnvelopeT rebuilds the SimpleEnvelope of a decoded
+// envelope, its AsJson is the canonical form of env. The ttl is taken as
+// received, 0 stays 0.
+func NewSimpleEnvelopeFromEnvelopeT(env *EnvelopeT, jsonProp *ogs.JsonProps) *SimpleEnvelope {
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		ID:       env.ID,
+		Src:      env.Src,
+		Dst:      env.Dst,
+		T:        env.T,
+		TTL:      int(env.TTL),
+		Data:     env.Data,
+		JsonProp: jsonProp,
+	})
+	se.simpleEnvelopeProps.receivedTTL = true
 	return se
 }
 
+// canonicalData is the data after the type mapping, it's mapped once by
+// BuildSimpleEnvelope.
+func (s *SimpleEnvelope) canonicalData() interface{} {
+	return s.simpleEnvelopeProps.canonical
+}
+
 func (s *SimpleEnvelope) AsDataJson() *string {
 	return s.DataJsonHash.JsonStr
 }
 
-func (s *SimpleEnvelope) toDataJson() *JsonHash {
-	var dataJsonStrings []string
+// hashCollector is an ogs.HashCollector which hashes null as "null" like
+// the TypeScript implementation, ogs would hash the %v of nil.
+type hashCollector struct {
+	*ogs.HashCollector
+}
 
+func newHashCollector() *hashCollector {
+	return &hashCollector{ogs.NewHashCollector()}
+}
+
+func (h *hashCollector) Append(sval ogs.SVal) {
+	if sval.OutState == ogs.VALUE && sval.Val != nil && sval.Val.AsValue() == nil {
+		sval.Val = ogs.JsonValType{Val: "null"}
+	}
+	h.HashCollector.Append(sval)
+}
+
+// streamData passes the data JSON in parts to out, the hash is only
+// calculated if withHash is set.
+func (s *SimpleEnvelope) streamData(out func(string), withHash bool) *string {
 	indent := 0
 	if s.simpleEnvelopeProps.JsonProp != nil {
 		indent = s.simpleEnvelopeProps.JsonProp.Indent
 	}
 	jpr := ogs.NewJsonProps(indent,
 		fmt.Sprintf("\n%v", strings.Repeat(" ", 2*indent)))
-	dataJsonC := ogs.NewJsonCollector(func(part string) {
-		dataJsonStrings = append(dataJsonStrings, part)
-	}, jpr)
-	var dataHashC *ogs.HashCollector
+	dataJsonC := ogs.NewJsonCollector(out, jpr)
+	var dataHashC *hashCollector
 	var dataProcessor ogs.SvalFn
-	if s.simpleEnvelopeProps.ID != "" {
+	if !withHash {
 		dataProcessor = func(sval ogs.SVal) {
 			dataJsonC.Append(sval)
 		}
 	} else {
-		dataHashC = ogs.NewHashCollector()
+		dataHashC = newHashCollector()
 		dataProcessor = func(sval ogs.SVal) {
 			dataHashC.Append(sval)
 			dataJsonC.Append(sval)
 		}
 	}
-	ogs.ObjectGraphStreamer(s.simpleEnvelopeProps.Data.Data, dataProcessor)
-	var hashVal *string
-	if dataHashC != nil {
-		hash := dataHashC.Digest()
-		hashVal = &hash
+	ogs.ObjectGraphStreamer(s.canonicalData(), dataProcessor)
+	if dataHashC == nil {
+		return nil
 	}
+	hash := dataHashC.Digest()
+	return &hash
+}
+
+func (s *SimpleEnvelope) toDataJson() *JsonHash {
+	var dataJsonStrings []string
+	hashVal := s.streamData(func(part string) {
+		dataJsonStrings = append(dataJsonStrings, part)
+	}, s.simpleEnvelopeProps.ID == "")
 	jsonStr := strings.Join(dataJsonStrings[:], "")
 	return &JsonHash{
 		JsonStr: &jsonStr,
@@ -172,33 +266,48 @@ func (s *SimpleEnvelope) toDataJson() *JsonHash {
 
 }
 
-func (s *SimpleEnvelope) lazy() *SimpleEnvelope {
-	s.DataJsonHash = s.toDataJson()
-	t := s.simpleEnvelopeProps.T
-	id := s.simpleEnvelopeProps.ID
-	if id == "" {
-		id = s.simpleEnvelopeProps.IdGenerator(
-			GeneratorProps{T: t, Hash: s.DataJsonHash.Hash, SimpleEnvelopeProps: s.simpleEnvelopeProps},
-		)
+func (s *SimpleEnvelope) generateID(hash *string) string {
+	if s.simpleEnvelopeProps.ID != "" {
+		return s.simpleEnvelopeProps.ID
 	}
+	return s.simpleEnvelopeProps.IdGenerator(
+		GeneratorProps{T: s.simpleEnvelopeProps.T, Hash: hash, SimpleEnvelopeProps: s.simpleEnvelopeProps},
+	)
+}
 
+// header is the envelope without data.data
+func (s *SimpleEnvelope) header(id string) *EnvelopeT {
 	ttl := s.simpleEnvelopeProps.TTL
-	if ttl == 0 {
+	if ttl == 0 && !s.simpleEnvelopeProps.receivedTTL {
 		ttl = 10
 	}
-	envelope := &EnvelopeT{
+	dst := s.simpleEnvelopeProps.Dst
+	if dst == nil {
+		dst = []string{}
+	}
+	return &EnvelopeT{
 		V:   V_A,
 		ID:  id,
 		Src: s.simpleEnvelopeProps.Src,
-		Dst: s.simpleEnvelopeProps.Dst,
-		T:   float64(t),
+		Dst: dst,
+		T:   float64(s.simpleEnvelopeProps.T),
 		TTL: float64(ttl),
 		Data: PayloadT1{
 			Kind: s.simpleEnvelopeProps.Data.Kind,
 		},
 	}
+}
 
-	ogs.ObjectGraphStreamer(*envelope, func(sval ogs.SVal) {
+func (s *SimpleEnvelope) lazy() *SimpleEnvelope {
+	if s.Envelope != nil {
+		// like the TS implementation the envelope is only rendered once
+		return s
+	}
+	s.DataJsonHash = s.toDataJson()
+	envelope := s.header(s.generateID(s.DataJsonHash.Hash))
+
+	// streamed as dict so that optional members like signatures are omitted
+	ogs.ObjectGraphStreamer(envelope.ToDict(), func(sval ogs.SVal) {
 		oval := sval
 		paths := strings.Join(sval.Paths, "")
 		// fmt.Fprintln(os.Stderr, "Path=", paths, sval.OutState.String())
@@ -227,6 +336,31 @@ func (s *SimpleEnvelope) lazy() *SimpleEnvelope {
 	return s
 }
 
+// DataHash is the hash of the data which the id generators get, it's
+// also calculated if the envelope was created with an ID.
+func (s *SimpleEnvelope) DataHash() string {
+	if s.DataJsonHash != nil && s.DataJsonHash.Hash != nil {
+		return *s.DataJsonHash.Hash
+	}
+	dataHashC := newHashCollector()
+	ogs.ObjectGraphStreamer(s.canonicalData(), func(sval ogs.SVal) {
+		dataHashC.Append(sval)
+	})
+	return dataHashC.Digest()
+}
+
+// VerifyID checks that the ID of env was generated by THashIdGenerator or
+// HashIdGenerator from its data.
+func VerifyID(env *EnvelopeT) error {
+	se := NewSimpleEnvelopeFromEnvelopeT(env, nil)
+	hash := se.DataHash()
+	props := GeneratorProps{T: int64(env.T), Hash: &hash, SimpleEnvelopeProps: se.simpleEnvelopeProps}
+	if env.ID == THashIdGenerator(props) || env.ID == HashIdGenerator(props) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s hash %s", ErrIDMismatch, env.ID, hash)
+}
+
 func (s *SimpleEnvelope) AsJson() *string {
 	if s.envJsonString == nil {
 		str := strings.Join(s.lazy().envJsonStrings, "")
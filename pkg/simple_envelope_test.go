@@ -3,6 +3,7 @@ package c5
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -244,3 +245,34 @@ type SvalFnMock struct {
 func (_m *SvalFnMock) Execute(prob ogs.SVal) {
 	_m.Called(prob)
 }
+
+func (s *SimpleEnvelopeSuite) TestDataHashWithGivenId() {
+	typ := SampleY{Y: 4}
+	env := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		ID:  "myId",
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "kind",
+			Data: typ.ToDict(),
+		},
+	})
+	assert.Equal(s.T(), "GUKeStj4aGQRju7p2Dzf31Qi2d2MVuRCw68H1c8gMCnQ", env.DataHash())
+}
+
+func (s *SimpleEnvelopeSuite) TestVerifyID() {
+	typ := SampleY{Y: 4}
+	for _, gen := range []IdGeneratorFn{THashIdGenerator, HashIdGenerator} {
+		env := NewSimpleEnvelope(&SimpleEnvelopeProps{
+			T:   123,
+			Src: "test case",
+			Data: PayloadT1{
+				Kind: "kind",
+				Data: typ.ToDict(),
+			},
+			IdGenerator: gen,
+		}).AsEnvelope()
+		assert.NoError(s.T(), VerifyID(env))
+		env.Data.Data = map[string]interface{}{"y": 5}
+		assert.True(s.T(), errors.Is(VerifyID(env), ErrIDMismatch))
+	}
+}
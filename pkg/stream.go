@@ -0,0 +1,63 @@
+package c5
+
+import (
+	"io"
+	"strings"
+
+	ogs "github.com/mabels/object-graph-streamer"
+)
+
+type partWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (p *partWriter) write(part string) {
+	if p.err == nil {
+		_, p.err = io.WriteString(p.w, part)
+	}
+}
+
+// WriteDataJson writes the data JSON, AsDataJson, to w and returns the
+// data hash. The JSON isn't built as one string.
+func (s *SimpleEnvelope) WriteDataJson(w io.Writer) (string, error) {
+	pw := &partWriter{w: w}
+	hash := s.streamData(pw.write, true)
+	return *hash, pw.err
+}
+
+// WriteJson writes the same bytes as AsJson to w in one pass without
+// building the JSON string. The data is passed to the hash collector and
+// w together, the id sorts after the data and is generated once the data
+// is written. The memory still grows with the data: the type mapping
+// copies the data graph and every value is escaped into a string of its
+// own.
+func (s *SimpleEnvelope) WriteJson(w io.Writer) error {
+	if s.envJsonString != nil {
+		_, err := io.WriteString(w, *s.envJsonString)
+		return err
+	}
+	pw := &partWriter{w: w}
+	envJsonC := ogs.NewJsonCollector(pw.write, s.simpleEnvelopeProps.JsonProp)
+	var hash *string
+	ogs.ObjectGraphStreamer(s.header("").ToDict(), func(sval ogs.SVal) {
+		paths := strings.Join(sval.Paths, "")
+		if strings.HasPrefix(paths, "{data{data") && sval.OutState != ogs.ATTRIBUTE {
+			if sval.OutState.String() == ogs.OBJECT_START {
+				// writes the attribute, the data follows directly
+				empty := ""
+				envJsonC.Append(ogs.SVal{
+					OutState: ogs.VALUE,
+					Val:      ogs.PlainValType{Val: &empty},
+				})
+				hash = s.streamData(pw.write, s.simpleEnvelopeProps.ID == "")
+			}
+			return
+		}
+		if paths == "{id" && sval.OutState == ogs.VALUE {
+			sval.Val = ogs.JsonValType{Val: s.generateID(hash)}
+		}
+		envJsonC.Append(sval)
+	})
+	return pw.err
+}
@@ -0,0 +1,101 @@
+package c5
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	ogs "github.com/mabels/object-graph-streamer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamSuite struct {
+	suite.Suite
+}
+
+func streamProps(data map[string]interface{}, id string, jsonProp *ogs.JsonProps) *SimpleEnvelopeProps {
+	return &SimpleEnvelopeProps{
+		ID:  id,
+		Src: "test case",
+		Dst: []string{"a", "b"},
+		Data: PayloadT1{
+			Kind: "stream",
+			Data: data,
+		},
+		JsonProp:      jsonProp,
+		TimeGenerator: mtimer,
+	}
+}
+
+func (s *StreamSuite) TestSameAsAsJson() {
+	data := map[string]interface{}{
+		"name":   "object",
+		"nested": map[string]interface{}{"y": 4, "x": []interface{}{1, "two", map[string]interface{}{"z": true}}},
+		"empty":  map[string]interface{}{},
+	}
+	for _, jsonProp := range []*ogs.JsonProps{nil, ogs.NewJsonProps(2, "\n")} {
+		for _, id := range []string{"", "given-id"} {
+			expected := NewSimpleEnvelope(streamProps(data, id, jsonProp))
+			out := bytes.Buffer{}
+			assert.NoError(s.T(), NewSimpleEnvelope(streamProps(data, id, jsonProp)).WriteJson(&out))
+			assert.Equal(s.T(), *expected.AsJson(), out.String())
+
+			dataOut := bytes.Buffer{}
+			hash, err := NewSimpleEnvelope(streamProps(data, id, jsonProp)).WriteDataJson(&dataOut)
+			assert.NoError(s.T(), err)
+			assert.Equal(s.T(), *expected.AsDataJson(), dataOut.String())
+			assert.Equal(s.T(), expected.DataHash(), hash)
+		}
+	}
+}
+
+func (s *StreamSuite) TestAfterAsJson() {
+	se := NewSimpleEnvelope(streamProps(map[string]interface{}{"a": 1}, "", nil))
+	expected := *se.AsJson()
+	out := bytes.Buffer{}
+	assert.NoError(s.T(), se.WriteJson(&out))
+	assert.Equal(s.T(), expected, out.String())
+}
+
+func TestStreamSuite(t *testing.T) {
+	suite.Run(t, new(StreamSuite))
+}
+
+var benchPayload map[string]interface{}
+
+// 100MB in 1000 distinct values of 100KB, the benchmarks compare the
+// allocations of AsJson and WriteJson.
+func benchmarkPayload() map[string]interface{} {
+	if benchPayload == nil {
+		benchPayload = map[string]interface{}{}
+		for i := 0; i < 1000; i++ {
+			benchPayload[fmt.Sprintf("k%04d", i)] = strings.Repeat(fmt.Sprintf("%04d", i), 25*1024)
+		}
+	}
+	return benchPayload
+}
+
+func BenchmarkAsJson100MB(b *testing.B) {
+	data := benchmarkPayload()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		se := NewSimpleEnvelope(streamProps(data, "", nil))
+		io.Discard.Write([]byte(*se.AsJson()))
+	}
+}
+
+func BenchmarkWriteJson100MB(b *testing.B) {
+	data := benchmarkPayload()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		se := NewSimpleEnvelope(streamProps(data, "", nil))
+		if err := se.WriteJson(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package c5
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	ogs "github.com/mabels/object-graph-streamer"
+)
+
+// testVector is a file of the shared testvectors directory.
+type testVector struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Props       struct {
+		ID          string                 `json:"id"`
+		Src         string                 `json:"src"`
+		Dst         []string               `json:"dst"`
+		T           float64                `json:"t"`
+		TTL         int                    `json:"ttl"`
+		IdGenerator string                 `json:"idGenerator"`
+		Data        map[string]interface{} `json:"data"`
+	} `json:"props"`
+	Expected struct {
+		DataJson string `json:"dataJson"`
+		DataHash string `json:"dataHash"`
+		ID       string `json:"id"`
+		Json     string `json:"json"`
+		Indented []struct {
+			Indent int    `json:"indent"`
+			Json   string `json:"json"`
+		} `json:"indented"`
+	} `json:"expected"`
+}
+
+type TestVectorsSuite struct {
+	suite.Suite
+	vectors []testVector
+}
+
+func (s *TestVectorsSuite) SetupSuite() {
+	fnames, err := filepath.Glob("../testvectors/*.json")
+	assert.NoError(s.T(), err)
+	assert.NotEmpty(s.T(), fnames)
+	for _, fname := range fnames {
+		data, err := os.ReadFile(fname)
+		assert.NoError(s.T(), err)
+		vector := testVector{}
+		assert.NoError(s.T(), json.Unmarshal(data, &vector), fname)
+		s.vectors = append(s.vectors, vector)
+	}
+}
+
+func (s *TestVectorsSuite) envelope(vector *testVector, jsonProp *ogs.JsonProps) *SimpleEnvelope {
+	props := SimpleEnvelopeProps{
+		ID:       vector.Props.ID,
+		Src:      vector.Props.Src,
+		Dst:      vector.Props.Dst,
+		T:        vector.Props.T,
+		TTL:      vector.Props.TTL,
+		Data:     vector.Props.Data,
+		JsonProp: jsonProp,
+	}
+	switch vector.Props.IdGenerator {
+	case "", "tHash":
+	case "hash":
+		props.IdGenerator = HashIdGenerator
+	default:
+		s.T().Fatalf("%s: unknown idGenerator %s", vector.Name, vector.Props.IdGenerator)
+	}
+	return NewSimpleEnvelope(&props)
+}
+
+func (s *TestVectorsSuite) TestEnvelopes() {
+	for i := range s.vectors {
+		vector := &s.vectors[i]
+		s.Run(vector.Name, func() {
+			se := s.envelope(vector, nil)
+			assert.Equal(s.T(), vector.Expected.Json, *se.AsJson())
+			assert.Equal(s.T(), vector.Expected.DataJson, *se.AsDataJson())
+			assert.Equal(s.T(), vector.Expected.DataHash, se.DataHash())
+			assert.Equal(s.T(), vector.Expected.ID, se.AsEnvelope().ID)
+
+			assert.Equal(s.T(), vector.Expected.Json, string(s.envelope(vector, nil).AppendJson(nil)))
+			out := bytes.Buffer{}
+			assert.NoError(s.T(), s.envelope(vector, nil).WriteJson(&out))
+			assert.Equal(s.T(), vector.Expected.Json, out.String())
+
+			for _, indented := range vector.Expected.Indented {
+				jsonProp := ogs.NewJsonProps(indented.Indent, "")
+				assert.Equal(s.T(), indented.Json, *s.envelope(vector, jsonProp).AsJson())
+				assert.Equal(s.T(), indented.Json, string(s.envelope(vector, jsonProp).AppendJson(nil)))
+			}
+		})
+	}
+}
+
+func (s *TestVectorsSuite) TestDecoded() {
+	for i := range s.vectors {
+		vector := &s.vectors[i]
+		s.Run(vector.Name, func() {
+			env, err := UnmarshalEnvelopeT([]byte(vector.Expected.Json))
+			assert.NoError(s.T(), err)
+			assert.Equal(s.T(), vector.Expected.Json, *NewSimpleEnvelopeFromEnvelopeT(env, nil).AsJson())
+			if vector.Props.ID == "" {
+				assert.NoError(s.T(), VerifyID(env))
+			}
+			raw, err := UnmarshalRawEnvelope([]byte(vector.Expected.Json))
+			assert.NoError(s.T(), err)
+			canonical, err := raw.IsCanonical()
+			assert.NoError(s.T(), err)
+			assert.True(s.T(), canonical)
+		})
+	}
+}
+
+// TestByteSlices feeds the Go values the byte-slices vector stands for.
+func (s *TestVectorsSuite) TestByteSlices() {
+	for i := range s.vectors {
+		vector := s.vectors[i]
+		if vector.Name != "byte-slices" {
+			continue
+		}
+		vector.Props.Data = map[string]interface{}{
+			"kind": vector.Props.Data["kind"],
+			"data": map[string]interface{}{
+				"bytes": []byte{0, 1, 2},
+				"empty": []byte{},
+				"nil":   []byte(nil),
+			},
+		}
+		assert.Equal(s.T(), vector.Expected.Json, *s.envelope(&vector, nil).AsJson())
+		assert.Equal(s.T(), vector.Expected.Json, string(s.envelope(&vector, nil).AppendJson(nil)))
+		return
+	}
+	s.T().Fatal("byte-slices vector missing")
+}
+
+// TestExactIntegers shows the divergence the README documents: decoded as
+// json.Number the integers above 2^53 keep their digits, the TypeScript
+// implementation and the vectors round them to a double.
+func (s *TestVectorsSuite) TestExactIntegers() {
+	data, err := os.ReadFile("../testvectors/large-integers.json")
+	assert.NoError(s.T(), err)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	vector := testVector{}
+	assert.NoError(s.T(), dec.Decode(&vector))
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:  vector.Props.Src,
+		T:    int64(vector.Props.T),
+		Data: vector.Props.Data,
+	})
+	assert.Contains(s.T(), *se.AsJson(), `"aboveMaxSafe":9007199254740993`)
+	assert.Contains(s.T(), *se.AsJson(), `"int64Max":9223372036854775807`)
+	assert.NotEqual(s.T(), vector.Expected.DataHash, se.DataHash())
+}
+
+func TestTestVectorsSuite(t *testing.T) {
+	suite.Run(t, new(TestVectorsSuite))
+}
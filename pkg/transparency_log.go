@@ -0,0 +1,321 @@
+package c5
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/btcsuite/btcutil/base58"
+)
+
+// TreeHeadKind is the kind of the signed tree head envelopes of a
+// TransparencyLog.
+const TreeHeadKind = "c5.tree-head"
+
+var (
+	ErrConsistencyProof = errors.New("consistency proof invalid")
+	ErrLogIndex         = errors.New("log index out of range")
+)
+
+// TransparencyLog is a Certificate Transparency style append-only log of
+// envelopes. The leaves of its Merkle tree are the envelope IDs, the
+// envelopes are stored one JSON per line in a local file.
+type TransparencyLog struct {
+	lock    sync.RWMutex
+	file    *os.File
+	signer  *Signer
+	leaves  [][]byte
+	offsets []int64
+}
+
+func logLeafHash(id string) []byte {
+	return merkleLeafHash([]byte(id))
+}
+
+// OpenTransparencyLog opens or creates the log file, signer signs the tree
+// heads.
+func OpenTransparencyLog(fname string, signer *Signer) (*TransparencyLog, error) {
+	file, err := os.OpenFile(fname, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	log := &TransparencyLog{file: file, signer: signer, offsets: []int64{0}}
+	if err := log.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return log, nil
+}
+
+func (l *TransparencyLog) load() error {
+	reader := bufio.NewReader(l.file)
+	offset := int64(0)
+	for {
+		line, err := reader.ReadBytes('\n')
+		offset += int64(len(line))
+		if len(bytes.TrimSpace(line)) > 0 {
+			env, perr := UnmarshalEnvelopeTUseNumber(line)
+			if perr != nil {
+				return fmt.Errorf("log entry %d: %v", len(l.leaves), perr)
+			}
+			l.leaves = append(l.leaves, logLeafHash(env.ID))
+			l.offsets = append(l.offsets, offset)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Append stores env and returns its index. The ID of env has to match its
+// data.
+func (l *TransparencyLog) Append(env *EnvelopeT) (int, error) {
+	if err := VerifyID(env); err != nil {
+		return 0, err
+	}
+	line, err := env.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	line = append(line, '\n')
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, err := l.file.Write(line); err != nil {
+		return 0, err
+	}
+	if err := l.file.Sync(); err != nil {
+		return 0, err
+	}
+	l.leaves = append(l.leaves, logLeafHash(env.ID))
+	l.offsets = append(l.offsets, l.offsets[len(l.offsets)-1]+int64(len(line)))
+	return len(l.leaves) - 1, nil
+}
+
+func (l *TransparencyLog) AppendSimpleEnvelope(se *SimpleEnvelope) (int, error) {
+	return l.Append(se.AsEnvelope())
+}
+
+func (l *TransparencyLog) Size() int {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	return len(l.leaves)
+}
+
+// Get reads the envelope at idx back from the file.
+func (l *TransparencyLog) Get(idx int) (*EnvelopeT, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if idx < 0 || idx >= len(l.leaves) {
+		return nil, fmt.Errorf("%w: %d", ErrLogIndex, idx)
+	}
+	return l.getLocked(idx)
+}
+
+func (l *TransparencyLog) checkSize(size int) error {
+	if size < 0 || size > len(l.leaves) {
+		return fmt.Errorf("%w: tree size %d", ErrLogIndex, size)
+	}
+	return nil
+}
+
+// Root is the base58 Merkle root over the first size entries.
+func (l *TransparencyLog) Root(size int) (string, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if err := l.checkSize(size); err != nil {
+		return "", err
+	}
+	return base58.Encode(merkleRoot(l.leaves[:size])), nil
+}
+
+// TreeHead returns the signed tree head of the current log as envelope of
+// kind TreeHeadKind with the data {"root": ..., "size": ...}.
+func (l *TransparencyLog) TreeHead() (*EnvelopeT, error) {
+	l.lock.RLock()
+	size := len(l.leaves)
+	root := base58.Encode(merkleRoot(l.leaves))
+	l.lock.RUnlock()
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: l.signer.Key.Src,
+		Data: PayloadT1{
+			Kind: TreeHeadKind,
+			Data: map[string]interface{}{
+				"root": root,
+				"size": size,
+			},
+		},
+		TimeGenerator: l.signer.TimeGenerator,
+	})
+	return l.signer.SignSimpleEnvelope(se)
+}
+
+// InclusionProof proves that the entry idx is part of the tree of size.
+func (l *TransparencyLog) InclusionProof(idx int, size int) (*InclusionProof, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if err := l.checkSize(size); err != nil {
+		return nil, err
+	}
+	if idx < 0 || idx >= size {
+		return nil, fmt.Errorf("%w: %d of tree size %d", ErrLogIndex, idx, size)
+	}
+	env, err := l.getLocked(idx)
+	if err != nil {
+		return nil, err
+	}
+	path := merkleInclusionPath(l.leaves[:size], idx)
+	proof := &InclusionProof{
+		Index: idx,
+		Size:  size,
+		Leaf:  env.ID,
+		Root:  base58.Encode(merkleRoot(l.leaves[:size])),
+		Path:  make([]string, len(path)),
+	}
+	for i, p := range path {
+		proof.Path[i] = base58.Encode(p)
+	}
+	return proof, nil
+}
+
+func (l *TransparencyLog) getLocked(idx int) (*EnvelopeT, error) {
+	line := make([]byte, l.offsets[idx+1]-l.offsets[idx])
+	if _, err := l.file.ReadAt(line, l.offsets[idx]); err != nil {
+		return nil, err
+	}
+	return UnmarshalEnvelopeTUseNumber(bytes.TrimSpace(line))
+}
+
+type ConsistencyProof struct {
+	First      int      `json:"first"`
+	Second     int      `json:"second"`
+	FirstRoot  string   `json:"firstRoot"`
+	SecondRoot string   `json:"secondRoot"`
+	Path       []string `json:"path"`
+}
+
+// ConsistencyProof proves that the tree of size first is a prefix of the
+// tree of size second.
+func (l *TransparencyLog) ConsistencyProof(first int, second int) (*ConsistencyProof, error) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if err := l.checkSize(second); err != nil {
+		return nil, err
+	}
+	if first < 0 || first > second {
+		return nil, fmt.Errorf("%w: tree size %d of %d", ErrLogIndex, first, second)
+	}
+	path := merkleConsistencyPath(first, l.leaves[:second])
+	proof := &ConsistencyProof{
+		First:      first,
+		Second:     second,
+		FirstRoot:  base58.Encode(merkleRoot(l.leaves[:first])),
+		SecondRoot: base58.Encode(merkleRoot(l.leaves[:second])),
+		Path:       make([]string, len(path)),
+	}
+	for i, p := range path {
+		proof.Path[i] = base58.Encode(p)
+	}
+	return proof, nil
+}
+
+func (l *TransparencyLog) Close() error {
+	return l.file.Close()
+}
+
+func (p *ConsistencyProof) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+func UnmarshalConsistencyProof(data []byte) (*ConsistencyProof, error) {
+	proof := ConsistencyProof{}
+	if err := json.Unmarshal(data, &proof); err != nil {
+		return nil, err
+	}
+	return &proof, nil
+}
+
+// verifyTreeHead checks ID and signatures of a tree head, its root and size
+// are worthless without.
+func verifyTreeHead(verifier EnvelopeVerifier, sth *EnvelopeT) error {
+	if err := VerifyID(sth); err != nil {
+		return fmt.Errorf("tree head: %w", err)
+	}
+	if _, err := verifier.Verify(sth); err != nil {
+		return fmt.Errorf("tree head: %w", err)
+	}
+	return nil
+}
+
+// treeHead returns size and root of a tree head envelope, the signature is
+// checked by verifyTreeHead.
+func treeHead(sth *EnvelopeT) (int, string, error) {
+	if sth.Data.Kind != TreeHeadKind {
+		return 0, "", fmt.Errorf("not a %s envelope", TreeHeadKind)
+	}
+	root, _ := sth.Data.Data["root"].(string)
+	size, ok := asInt(sth.Data.Data["size"])
+	if !ok {
+		return 0, "", fmt.Errorf("%s without size", TreeHeadKind)
+	}
+	return size, root, nil
+}
+
+// VerifyLogInclusion checks that env is the proven entry of the tree
+// committed by the tree head sth, which has to pass verifier.
+func VerifyLogInclusion(verifier EnvelopeVerifier, env *EnvelopeT, proof *InclusionProof, sth *EnvelopeT) error {
+	if err := verifyTreeHead(verifier, sth); err != nil {
+		return err
+	}
+	size, root, err := treeHead(sth)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInclusionProof, err)
+	}
+	if size != proof.Size || root != proof.Root {
+		return fmt.Errorf("%w: proof is not for tree size %d", ErrInclusionProof, size)
+	}
+	if env.ID != proof.Leaf {
+		return fmt.Errorf("%w: id %s is not the leaf %s", ErrInclusionProof, env.ID, proof.Leaf)
+	}
+	if err := VerifyID(env); err != nil {
+		return fmt.Errorf("%w: %v", ErrInclusionProof, err)
+	}
+	return proof.verify(logLeafHash(env.ID))
+}
+
+// VerifyConsistency checks that the tree of the tree head newer extends the
+// tree of older without modifying it, both tree heads have to pass verifier.
+func VerifyConsistency(verifier EnvelopeVerifier, proof *ConsistencyProof, older *EnvelopeT, newer *EnvelopeT) error {
+	for _, sth := range []*EnvelopeT{older, newer} {
+		if err := verifyTreeHead(verifier, sth); err != nil {
+			return err
+		}
+	}
+	firstSize, firstRoot, err := treeHead(older)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConsistencyProof, err)
+	}
+	secondSize, secondRoot, err := treeHead(newer)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConsistencyProof, err)
+	}
+	if proof.First != firstSize || proof.FirstRoot != firstRoot ||
+		proof.Second != secondSize || proof.SecondRoot != secondRoot {
+		return fmt.Errorf("%w: proof is not for the tree heads", ErrConsistencyProof)
+	}
+	path := make([][]byte, len(proof.Path))
+	for i, h := range proof.Path {
+		path[i] = base58.Decode(h)
+	}
+	if !verifyMerkleConsistency(firstSize, secondSize, path, base58.Decode(firstRoot), base58.Decode(secondRoot)) {
+		return fmt.Errorf("%w: %d to %d", ErrConsistencyProof, firstSize, secondSize)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package c5
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransparencyLogSuite struct {
+	suite.Suite
+	fname    string
+	signer   *Signer
+	verifier *Verifier
+}
+
+func (s *TransparencyLogSuite) SetupTest() {
+	s.fname = filepath.Join(s.T().TempDir(), "log.ndjson")
+	key, err := GenerateKey(AlgEdDSA, "log")
+	assert.NoError(s.T(), err)
+	s.signer = NewSigner(key)
+	s.signer.TimeGenerator = mtimer
+	kr := NewKeyring(nil)
+	assert.NoError(s.T(), kr.Add(key.PublicOnly()))
+	s.verifier = NewVerifier(kr, nil)
+}
+
+func (s *TransparencyLogSuite) envelope(i int) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: "test case",
+		Data: PayloadT1{
+			Kind: "event",
+			Data: map[string]interface{}{"i": i},
+		},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *TransparencyLogSuite) open(n int) *TransparencyLog {
+	log, err := OpenTransparencyLog(s.fname, s.signer)
+	assert.NoError(s.T(), err)
+	for i := log.Size(); i < n; i++ {
+		idx, err := log.AppendSimpleEnvelope(s.envelope(i))
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), i, idx)
+	}
+	return log
+}
+
+func (s *TransparencyLogSuite) TestInclusion() {
+	log := s.open(7)
+	defer log.Close()
+	sth, err := log.TreeHead()
+	assert.NoError(s.T(), err)
+	_, err = s.verifier.Verify(sth)
+	assert.NoError(s.T(), err)
+	for idx := 0; idx < 7; idx++ {
+		env, err := log.Get(idx)
+		assert.NoError(s.T(), err)
+		assert.Equal(s.T(), s.envelope(idx).AsEnvelope().ID, env.ID)
+		proof, err := log.InclusionProof(idx, 7)
+		assert.NoError(s.T(), err)
+		assert.NoError(s.T(), VerifyLogInclusion(s.verifier, env, proof, sth))
+	}
+	env, _ := log.Get(1)
+	proof, _ := log.InclusionProof(2, 7)
+	assert.True(s.T(), errors.Is(VerifyLogInclusion(s.verifier, env, proof, sth), ErrInclusionProof))
+	_, err = log.Get(7)
+	assert.True(s.T(), errors.Is(err, ErrLogIndex))
+
+	env, _ = log.Get(0)
+	proof, _ = log.InclusionProof(0, 7)
+	forged, err := log.TreeHead()
+	assert.NoError(s.T(), err)
+	forged.Signatures = nil
+	assert.True(s.T(), errors.Is(VerifyLogInclusion(s.verifier, env, proof, forged), ErrNoSignatures))
+	other, _ := GenerateKey(AlgEdDSA, "log")
+	otherSigner := NewSigner(other)
+	otherSigner.TimeGenerator = mtimer
+	forged, err = otherSigner.Sign(forged)
+	assert.NoError(s.T(), err)
+	assert.Error(s.T(), VerifyLogInclusion(s.verifier, env, proof, forged))
+}
+
+func (s *TransparencyLogSuite) TestLargeInteger() {
+	log := s.open(2)
+	se := NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "event", Data: map[string]interface{}{"i": int64(9007199254740993)}},
+		TimeGenerator: mtimer,
+	})
+	idx, err := log.AppendSimpleEnvelope(se)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), log.Close())
+
+	log = s.open(0)
+	defer log.Close()
+	assert.Equal(s.T(), 3, log.Size())
+	env, err := log.Get(idx)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), se.AsEnvelope().ID, env.ID)
+	sth, err := log.TreeHead()
+	assert.NoError(s.T(), err)
+	proof, err := log.InclusionProof(idx, 3)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), VerifyLogInclusion(s.verifier, env, proof, sth))
+}
+
+func (s *TransparencyLogSuite) TestConsistency() {
+	log := s.open(3)
+	older, err := log.TreeHead()
+	assert.NoError(s.T(), err)
+	log.Close()
+
+	log = s.open(10)
+	defer log.Close()
+	assert.Equal(s.T(), 10, log.Size())
+	newer, err := log.TreeHead()
+	assert.NoError(s.T(), err)
+	proof, err := log.ConsistencyProof(3, 10)
+	assert.NoError(s.T(), err)
+	data, err := proof.Marshal()
+	assert.NoError(s.T(), err)
+	proof, err = UnmarshalConsistencyProof(data)
+	assert.NoError(s.T(), err)
+	assert.NoError(s.T(), VerifyConsistency(s.verifier, proof, older, newer))
+
+	other, err := log.ConsistencyProof(4, 10)
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), errors.Is(VerifyConsistency(s.verifier, other, older, newer), ErrConsistencyProof))
+	proof.Path[0] = proof.Path[len(proof.Path)-1]
+	assert.True(s.T(), errors.Is(VerifyConsistency(s.verifier, proof, older, newer), ErrConsistencyProof))
+
+	proof, _ = log.ConsistencyProof(3, 10)
+	newer.Data.Data["root"] = older.Data.Data["root"]
+	assert.True(s.T(), errors.Is(VerifyConsistency(s.verifier, proof, older, newer), ErrIDMismatch))
+}
+
+func (s *TransparencyLogSuite) TestRejectsBadID() {
+	log := s.open(0)
+	defer log.Close()
+	env := s.envelope(1).AsEnvelope()
+	env.Data.Data["i"] = 2
+	_, err := log.Append(env)
+	assert.True(s.T(), errors.Is(err, ErrIDMismatch))
+	assert.Equal(s.T(), 0, log.Size())
+}
+
+func TestTransparencyLogSuite(t *testing.T) {
+	suite.Run(t, new(TransparencyLogSuite))
+}
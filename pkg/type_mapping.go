@@ -0,0 +1,325 @@
+package c5
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type mapping
+//
+// Before the data is hashed and rendered every Go value is mapped to the
+// JSON types the TypeScript implementation sees:
+//
+//   - a registered ValueSerializer of the type comes first, see Serializers
+//   - numbers are canonical, see CanonicalNumber
+//   - time.Time is the UTC JavaScript ISO string, JSISOStringMillisFormat
+//   - []byte is standard base64 like encoding/json, a nil []byte is null
+//   - json.Marshaler and encoding.TextMarshaler are honored
+//   - pointers and interfaces are dereferenced, nil is null
+//   - structs are objects named by their json tags with "-", omitempty
+//     and embedded structs like encoding/json
+//   - maps with string or integer keys are objects
+//   - other slices and arrays are arrays, a nil slice is null like a nil
+//     []byte
+//
+// Values which have none of these types are left to the object graph
+// streamer. A failing marshaler or a map key which is no string or
+// integer is an error, BuildSimpleEnvelope returns it.
+
+var ErrUnsupportedValue = errors.New("unsupported value")
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// typeMapper maps values with the serializers of an envelope, nil
+// serializers only use the DefaultSerializers. The first error is kept in
+// err, the failed value is mapped to nil.
+type typeMapper struct {
+	serializers *Serializers
+	err         error
+}
+
+func (m *typeMapper) fail(err error) interface{} {
+	if m.err == nil {
+		m.err = err
+	}
+	return nil
+}
+
+// canonicalizeData returns a copy of v with every value mapped to its JSON
+// type and numbers replaced by their CanonicalNumber. json.Number
+// marshals to the plain literal and its %v is the literal as well, so JSON
+// and hash of the object graph streamer both see the canonical form.
+func canonicalizeData(v interface{}) interface{} {
+	return (&typeMapper{}).data(v)
+}
+
+func (m *typeMapper) data(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		ret := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			ret[key] = m.data(val)
+		}
+		return ret
+	case []interface{}:
+		if t == nil {
+			return nil
+		}
+		ret := make([]interface{}, len(t))
+		for idx, val := range t {
+			ret[idx] = m.data(val)
+		}
+		return ret
+	case nil, string, bool:
+		return v
+	case float64, int, int64, json.Number:
+		if num, ok := CanonicalNumber(v); ok {
+			return num
+		}
+		return v
+	}
+	typ := reflect.TypeOf(v)
+	if fn, ok := m.serializer(typ); ok {
+		ret := fn(v)
+		if reflect.TypeOf(ret) == typ {
+			// a serializer which normalizes its own type
+			return m.builtin(ret)
+		}
+		return m.data(ret)
+	}
+	return m.builtin(v)
+}
+
+func (m *typeMapper) serializer(typ reflect.Type) (ValueSerializer, bool) {
+	if m.serializers != nil {
+		if fn, ok := m.serializers.lookup(typ); ok {
+			return fn, true
+		}
+	}
+	return DefaultSerializers.lookup(typ)
+}
+
+// builtin applies the type mapping rules without serializers.
+func (m *typeMapper) builtin(v interface{}) interface{} {
+	switch t := v.(type) {
+	case time.Time:
+		return t.UTC().Format(JSISOStringMillisFormat)
+	case []byte:
+		if t == nil {
+			return nil
+		}
+		return base64.StdEncoding.EncodeToString(t)
+	}
+	if num, ok := CanonicalNumber(v); ok {
+		return num
+	}
+	return m.value(reflect.ValueOf(v))
+}
+
+func (m *typeMapper) marshaler(typ reflect.Type, out []byte, err error) interface{} {
+	if err != nil {
+		return m.fail(fmt.Errorf("%v: %w", typ, err))
+	}
+	var ret interface{}
+	dec := json.NewDecoder(bytes.NewReader(out))
+	dec.UseNumber()
+	if err := dec.Decode(&ret); err != nil {
+		return m.fail(fmt.Errorf("%v: %w", typ, err))
+	}
+	return m.data(ret)
+}
+
+func (m *typeMapper) value(valOf reflect.Value) interface{} {
+	typ := valOf.Type()
+	if valOf.Kind() == reflect.Ptr {
+		if valOf.IsNil() {
+			return nil
+		}
+		// methods with value receiver are taken after dereferencing, so
+		// *time.Time is mapped like time.Time
+		elem := typ.Elem()
+		if !elem.Implements(jsonMarshalerType) && !elem.Implements(textMarshalerType) {
+			if !typ.Implements(jsonMarshalerType) && !typ.Implements(textMarshalerType) {
+				return m.data(valOf.Elem().Interface())
+			}
+		} else {
+			return m.data(valOf.Elem().Interface())
+		}
+	}
+	if typ.Implements(jsonMarshalerType) {
+		out, err := valOf.Interface().(json.Marshaler).MarshalJSON()
+		return m.marshaler(typ, out, err)
+	}
+	if typ.Implements(textMarshalerType) {
+		text, err := valOf.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return m.fail(fmt.Errorf("%v: %w", typ, err))
+		}
+		return string(text)
+	}
+	switch valOf.Kind() {
+	case reflect.Interface:
+		if valOf.IsNil() {
+			return nil
+		}
+		return m.data(valOf.Elem().Interface())
+	case reflect.String:
+		return valOf.String()
+	case reflect.Bool:
+		return valOf.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(valOf.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return json.Number(strconv.FormatUint(valOf.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		if num, ok := CanonicalNumber(valOf.Float()); ok {
+			return num
+		}
+	case reflect.Slice:
+		if valOf.IsNil() {
+			return nil
+		}
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(valOf.Bytes())
+		}
+		return m.list(valOf)
+	case reflect.Array:
+		return m.list(valOf)
+	case reflect.Map:
+		return m.dict(valOf)
+	case reflect.Struct:
+		ret := map[string]interface{}{}
+		m.object(valOf, ret)
+		return ret
+	}
+	return valOf.Interface()
+}
+
+func (m *typeMapper) list(valOf reflect.Value) []interface{} {
+	ret := make([]interface{}, valOf.Len())
+	for idx := range ret {
+		ret[idx] = m.data(valOf.Index(idx).Interface())
+	}
+	return ret
+}
+
+func (m *typeMapper) dict(valOf reflect.Value) interface{} {
+	ret := make(map[string]interface{}, valOf.Len())
+	iter := valOf.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+		var name string
+		switch key.Kind() {
+		case reflect.String:
+			name = key.String()
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			name = strconv.FormatInt(key.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			name = strconv.FormatUint(key.Uint(), 10)
+		default:
+			return m.fail(fmt.Errorf("%w: map key type %v", ErrUnsupportedValue, key.Type()))
+		}
+		ret[name] = m.data(iter.Value().Interface())
+	}
+	return ret
+}
+
+type jsonField struct {
+	name      string
+	omitEmpty bool
+}
+
+func parseJsonTag(fl reflect.StructField) (jsonField, bool) {
+	tag, hasTag := fl.Tag.Lookup("json")
+	if tag == "-" {
+		return jsonField{}, false
+	}
+	parts := strings.Split(tag, ",")
+	field := jsonField{name: parts[0]}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			field.omitEmpty = true
+		}
+	}
+	if !hasTag || field.name == "" {
+		field.name = fl.Name
+	}
+	return field, true
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// object adds the fields of valOf to ret, untagged embedded
+// structs are inlined and their fields lose against the outer ones.
+func (m *typeMapper) object(valOf reflect.Value, ret map[string]interface{}) {
+	typ := valOf.Type()
+	embedded := []reflect.Value{}
+	for i := 0; i < valOf.NumField(); i++ {
+		fl := typ.Field(i)
+		field, ok := parseJsonTag(fl)
+		if !ok {
+			continue
+		}
+		fv := valOf.Field(i)
+		if fl.Anonymous {
+			_, tagged := fl.Tag.Lookup("json")
+			ft := fl.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if !tagged && ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				embedded = append(embedded, fv)
+				continue
+			}
+		}
+		if fl.PkgPath != "" {
+			continue
+		}
+		if field.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		ret[field.name] = m.data(fv.Interface())
+	}
+	for _, fv := range embedded {
+		inner := map[string]interface{}{}
+		m.object(fv, inner)
+		for key, val := range inner {
+			if _, ok := ret[key]; !ok {
+				ret[key] = val
+			}
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package c5
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TypeMappingSuite struct {
+	suite.Suite
+}
+
+// the ids and hashes are the ones of src/simpleEnvelope.test.ts and the
+// TypeScript object-graph-streamer tests
+const tsSerializationID = "1624140000000-BbYxQMurpUmj1W6E4EwYM79Rm3quSz1wwtNZDSsFt1bp"
+
+type mappingNameDate struct {
+	Name    string `json:"name"`
+	Date    string `json:"date"`
+	Skipped string `json:"-"`
+	Empty   string `json:"empty,omitempty"`
+	private int
+}
+
+type mappingEmbedded struct {
+	mappingInner
+	Name string `json:"name"`
+}
+
+type mappingInner struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type mappingMarshaler struct{}
+
+func (mappingMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{"date":"2021-05-20","name":"object"}`), nil
+}
+
+var errMappingFailed = errors.New("mapping failed")
+
+type mappingFailing struct{}
+
+func (mappingFailing) MarshalJSON() ([]byte, error) {
+	return nil, errMappingFailed
+}
+
+type mappingDates struct {
+	X    map[string]interface{} `json:"x"`
+	Y    struct{}               `json:"y"`
+	Z    []string               `json:"z"`
+	Date *time.Time             `json:"date"`
+}
+
+func (s *TypeMappingSuite) id(data interface{}) string {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"value": data}},
+		TimeGenerator: mtimer,
+	}).AsEnvelope().ID
+}
+
+func (s *TypeMappingSuite) envelope(data map[string]interface{}) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src:           "test case",
+		Dst:           []string{},
+		TTL:           10,
+		Data:          PayloadT1{Kind: "test", Data: data},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *TypeMappingSuite) TestStructs() {
+	for _, data := range []interface{}{
+		mappingNameDate{Name: "object", Date: "2021-05-20", Skipped: "x", private: 1},
+		&mappingNameDate{Name: "object", Date: "2021-05-20"},
+		mappingEmbedded{mappingInner: mappingInner{Date: "2021-05-20", Name: "hidden"}, Name: "object"},
+		mappingMarshaler{},
+		map[string]string{"name": "object", "date": "2021-05-20"},
+	} {
+		mapped := canonicalizeData(data).(map[string]interface{})
+		se := s.envelope(mapped)
+		assert.Equal(s.T(), tsSerializationID, se.AsEnvelope().ID, "%T", data)
+	}
+	assert.Equal(s.T(), s.id(map[string]interface{}{"name": "object", "date": "2021-05-20"}),
+		s.id(&mappingNameDate{Name: "object", Date: "2021-05-20"}))
+}
+
+func (s *TypeMappingSuite) TestTime() {
+	loc := time.FixedZone("CEST", 2*60*60)
+	date := time.UnixMilli(444).In(loc)
+	data := map[string]interface{}{
+		"x":    map[string]interface{}{"y": 2, "z": "x"},
+		"y":    map[string]interface{}{},
+		"z":    []interface{}{},
+		"date": date,
+	}
+	assert.Equal(s.T(), "ECVWfmcNaUGkgvPZe7CojrnRNULxNczKXU8PGns6UDvr", s.envelope(data).DataHash())
+	data["date"] = &date
+	se := s.envelope(data)
+	assert.Equal(s.T(), "ECVWfmcNaUGkgvPZe7CojrnRNULxNczKXU8PGns6UDvr", se.DataHash())
+	assert.Contains(s.T(), *se.AsJson(), `"date":"1970-01-01T00:00:00.444Z"`)
+
+	typed := mappingDates{X: map[string]interface{}{"y": 2, "z": "x"}, Z: []string{}, Date: &date}
+	assert.Equal(s.T(), s.id(data), s.id(typed))
+
+	assert.Equal(s.T(), "2021-06-20T10:00:00.000Z", canonicalizeData(time.UnixMilli(1624183200000)))
+}
+
+func (s *TypeMappingSuite) TestBytesAndPointers() {
+	str := "value"
+	var nilPtr *mappingNameDate
+	mapped := canonicalizeData(map[string]interface{}{
+		"bytes": []byte("hello"),
+		"empty": []byte{},
+		"none":  []byte(nil),
+		"ptr":   &str,
+		"nil":   nilPtr,
+		"raw":   json.RawMessage(`{"a":[1,2.50]}`),
+		"ints":  map[int]uint8{1: 2},
+		"slice": []string(nil),
+		"list":  []interface{}(nil),
+	})
+	assert.Equal(s.T(), map[string]interface{}{
+		"bytes": "aGVsbG8=",
+		"empty": "",
+		"none":  nil,
+		"ptr":   "value",
+		"nil":   nil,
+		"raw":   map[string]interface{}{"a": []interface{}{json.Number("1"), json.Number("2.5")}},
+		"ints":  map[string]interface{}{"1": json.Number("2")},
+		"slice": nil,
+		"list":  nil,
+	}, mapped)
+}
+
+func (s *TypeMappingSuite) TestErrors() {
+	for value, expected := range map[interface{}]error{
+		mappingFailing{}:                     errMappingFailed,
+		&map[bool]int{true: 1}:               ErrUnsupportedValue,
+		&[]interface{}{json.RawMessage(`{`)}: nil,
+	} {
+		_, err := BuildSimpleEnvelope(&SimpleEnvelopeProps{
+			Src:           "test case",
+			Data:          PayloadT1{Kind: "test", Data: map[string]interface{}{"value": value}},
+			TimeGenerator: mtimer,
+		})
+		assert.Error(s.T(), err, "%T", value)
+		if expected != nil {
+			assert.ErrorIs(s.T(), err, expected)
+		}
+	}
+	assert.Panics(s.T(), func() { s.envelope(map[string]interface{}{"value": mappingFailing{}}) })
+}
+
+func (s *TypeMappingSuite) TestNullHash() {
+	// TypeScript hashes ""+null
+	sum := sha256.Sum256([]byte("anulltrue"))
+	data := map[string]interface{}{"a": []interface{}{nil, true}}
+	se := s.envelope(data)
+	assert.Equal(s.T(), base58.Encode(sum[:]), se.DataHash())
+	assert.Contains(s.T(), *se.AsJson(), `"a":[null,true]`)
+	fast := s.envelope(data)
+	assert.Equal(s.T(), *se.AsJson(), string(fast.AppendJson(nil)))
+}
+
+func (s *TypeMappingSuite) TestFastPath() {
+	date := time.UnixMilli(444)
+	se := s.envelope(map[string]interface{}{
+		"struct": mappingNameDate{Name: "object", Date: "2021-05-20"},
+		"dates":  mappingDates{Date: &date},
+		"bytes":  []byte{0, 1, 2},
+		"raw":    json.RawMessage(`[true,null]`),
+		"time":   date,
+	})
+	fast := s.envelope(se.AsEnvelope().Data.Data)
+	assert.Equal(s.T(), *se.AsJson(), string(fast.AppendJson(nil)))
+}
+
+func TestTypeMappingSuite(t *testing.T) {
+	suite.Run(t, new(TypeMappingSuite))
+}
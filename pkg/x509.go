@@ -0,0 +1,216 @@
+package c5
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNoCertificate      = errors.New("no certificate")
+	ErrCertSrcMismatch    = errors.New("certificate does not match src")
+	ErrCertKeyMismatch    = errors.New("certificate does not match key")
+	ErrCertificateChain   = errors.New("certificate chain invalid")
+	ErrUnknownCertificate = errors.New("unknown certificate")
+)
+
+// CertFingerprint is the x5t#S256 of cert: base64url SHA-256 of its DER.
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return b64url.EncodeToString(sum[:])
+}
+
+// CertIdentities are the names a certificate vouches for: URI and DNS
+// SANs, the common name only if there are no SANs at all.
+func CertIdentities(cert *x509.Certificate) []string {
+	ret := []string{}
+	for _, uri := range cert.URIs {
+		ret = append(ret, uri.String())
+	}
+	ret = append(ret, cert.DNSNames...)
+	if len(ret) == 0 && cert.Subject.CommonName != "" {
+		ret = append(ret, cert.Subject.CommonName)
+	}
+	return ret
+}
+
+func certMatchesSrc(cert *x509.Certificate, src string) error {
+	ids := CertIdentities(cert)
+	for _, id := range ids {
+		if id == src {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q not in %v", ErrCertSrcMismatch, src, ids)
+}
+
+// NewCertSigner signs with priv, which has to match the leaf chain[0].
+// The key ID is the certificate fingerprint and Src its first identity.
+// With embedChain the whole chain goes into x5c, otherwise only the
+// fingerprint is sent and the verifier has to know the certificate.
+func NewCertSigner(priv crypto.Signer, chain []*x509.Certificate, embedChain bool) (*Signer, error) {
+	if len(chain) == 0 {
+		return nil, ErrNoCertificate
+	}
+	leaf := chain[0]
+	ids := CertIdentities(leaf)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: certificate has no identity", ErrCertSrcMismatch)
+	}
+	key, err := NewKey(priv, ids[0])
+	if err != nil {
+		return nil, err
+	}
+	leafKey, err := NewKey(leaf.PublicKey, ids[0])
+	if err != nil {
+		return nil, err
+	}
+	if key.ID != leafKey.ID {
+		return nil, ErrCertKeyMismatch
+	}
+	key.ID = CertFingerprint(leaf)
+	key.NotBefore = leaf.NotBefore
+	key.NotAfter = leaf.NotAfter
+	signer := NewSigner(key)
+	signer.Chain = chain
+	signer.EmbedChain = embedChain
+	return signer, nil
+}
+
+// CertVerifier verifies X.509 signatures against a root pool. The Src of
+// the envelope (or the signer of a countersignature) has to be one of the
+// identities of the leaf certificate.
+//
+// The chain has to be valid at the verification time of TimeGenerator,
+// the t of a signature is chosen by the signer and only has to lie in the
+// validity of the leaf as well.
+type CertVerifier struct {
+	Roots         *x509.CertPool
+	Intermediates *x509.CertPool
+	Policy        *SignaturePolicy
+	// KeyUsages the leaf has to allow, ExtKeyUsageAny if empty
+	KeyUsages     []x509.ExtKeyUsage
+	TimeGenerator TimeGenerator
+	known         map[string]*x509.Certificate
+}
+
+func NewCertVerifier(roots *x509.CertPool, policy *SignaturePolicy) *CertVerifier {
+	if policy == nil {
+		policy = &SignaturePolicy{}
+	}
+	return &CertVerifier{
+		Roots:         roots,
+		Intermediates: x509.NewCertPool(),
+		Policy:        policy,
+		TimeGenerator: &realTimer{},
+		known:         map[string]*x509.Certificate{},
+	}
+}
+
+// AddCertificates makes certificates known for signatures which only
+// carry the fingerprint; they are also used as intermediates.
+func (cv *CertVerifier) AddCertificates(certs ...*x509.Certificate) {
+	for _, cert := range certs {
+		cv.known[CertFingerprint(cert)] = cert
+		cv.Intermediates.AddCert(cert)
+	}
+}
+
+func (cv *CertVerifier) leaf(sig *SignatureT) (*x509.Certificate, *x509.CertPool, error) {
+	if len(sig.X5C) == 0 {
+		if sig.X5TS256 == "" {
+			return nil, nil, ErrNoCertificate
+		}
+		leaf, found := cv.known[sig.X5TS256]
+		if !found {
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnknownCertificate, sig.X5TS256)
+		}
+		return leaf, cv.Intermediates, nil
+	}
+	certs := make([]*x509.Certificate, len(sig.X5C))
+	for idx, b64 := range sig.X5C {
+		der, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrCertificateChain, err)
+		}
+		certs[idx], err = x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrCertificateChain, err)
+		}
+	}
+	if sig.X5TS256 != "" && sig.X5TS256 != CertFingerprint(certs[0]) {
+		return nil, nil, fmt.Errorf("%w: x5t#S256 does not match x5c", ErrCertificateChain)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	for _, cert := range cv.known {
+		intermediates.AddCert(cert)
+	}
+	return certs[0], intermediates, nil
+}
+
+func (cv *CertVerifier) verifySignature(env *EnvelopeT, canonical string, idx int) SignatureResult {
+	sig := env.Signatures[idx]
+	res := SignatureResult{Index: idx, Signature: sig}
+	leaf, intermediates, err := cv.leaf(&sig)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	now := time.Now()
+	if cv.TimeGenerator != nil {
+		now = cv.TimeGenerator.Now()
+	}
+	keyUsages := cv.KeyUsages
+	if len(keyUsages) == 0 {
+		keyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         cv.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     keyUsages,
+	})
+	if err != nil {
+		res.Err = fmt.Errorf("%w: %v", ErrCertificateChain, err)
+		return res
+	}
+	signedAt := time.UnixMilli(int64(sig.T))
+	if signedAt.Before(leaf.NotBefore) || signedAt.After(leaf.NotAfter) {
+		res.Err = fmt.Errorf("%w: signed at %s outside of the validity of the certificate", ErrCertificateChain,
+			signedAt.UTC().Format(JSISOStringMillisFormat))
+		return res
+	}
+	res.Chain = chains[0]
+	src := env.Src
+	if sig.Counter {
+		src = sig.Src
+	}
+	if err := certMatchesSrc(leaf, src); err != nil {
+		res.Err = err
+		return res
+	}
+	key, err := NewKey(leaf.PublicKey, src)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	key.ID = CertFingerprint(leaf)
+	res.Key = key
+	res.Err = checkSignature(key, canonical, &sig, env.Signatures[:idx])
+	return res
+}
+
+// Verify checks all signatures of env like Verifier.Verify does, policy
+// Kids are certificate fingerprints.
+func (cv *CertVerifier) Verify(env *EnvelopeT) ([]SignatureResult, error) {
+	return verifyAll(env, cv.Policy, func(canonical string, idx int) SignatureResult {
+		return cv.verifySignature(env, canonical, idx)
+	})
+}
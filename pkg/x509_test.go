@@ -0,0 +1,195 @@
+package c5
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type X509Suite struct {
+	suite.Suite
+	root         *x509.Certificate
+	intermediate *x509.Certificate
+	leaf         *x509.Certificate
+	leafKey      crypto.Signer
+	roots        *x509.CertPool
+}
+
+func (s *X509Suite) certificate(cn string, uri string, isCA bool, parent *x509.Certificate, parentKey crypto.Signer) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(s.T(), err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             mtimer.Now().Add(-time.Hour),
+		NotAfter:              mtimer.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if !isCA {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	if uri != "" {
+		u, _ := url.Parse(uri)
+		tmpl.URIs = []*url.URL{u}
+	}
+	if parent == nil {
+		parent = tmpl
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, key.Public(), parentKey)
+	assert.NoError(s.T(), err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(s.T(), err)
+	return cert, key
+}
+
+func (s *X509Suite) SetupTest() {
+	var rootKey, intermediateKey crypto.Signer
+	s.root, rootKey = s.certificate("root", "", true, nil, nil)
+	s.intermediate, intermediateKey = s.certificate("intermediate", "", true, s.root, rootKey)
+	s.leaf, s.leafKey = s.certificate("orders", "spiffe://acme/orders", false, s.intermediate, intermediateKey)
+	s.roots = x509.NewCertPool()
+	s.roots.AddCert(s.root)
+}
+
+func (s *X509Suite) envelope(src string) *SimpleEnvelope {
+	return NewSimpleEnvelope(&SimpleEnvelopeProps{
+		Src: src,
+		Data: PayloadT1{
+			Kind: "kind",
+			Data: map[string]interface{}{"y": 4},
+		},
+		TimeGenerator: mtimer,
+	})
+}
+
+func (s *X509Suite) signer(embed bool) *Signer {
+	signer, err := NewCertSigner(s.leafKey, []*x509.Certificate{s.leaf, s.intermediate}, embed)
+	assert.NoError(s.T(), err)
+	signer.TimeGenerator = mtimer
+	return signer
+}
+
+// offsetTimer is mtimer moved by the duration.
+type offsetTimer time.Duration
+
+func (o offsetTimer) Now() time.Time {
+	return mtimer.Now().Add(time.Duration(o))
+}
+
+// verifier verifies at the time the certificates are valid.
+func (s *X509Suite) verifier(roots *x509.CertPool) *CertVerifier {
+	cv := NewCertVerifier(roots, nil)
+	cv.TimeGenerator = mtimer
+	return cv
+}
+
+func (s *X509Suite) TestEmbeddedChain() {
+	env, err := s.signer(true).SignSimpleEnvelope(s.envelope("spiffe://acme/orders"))
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), env.Signatures[0].X5C, 2)
+	assert.Equal(s.T(), CertFingerprint(s.leaf), env.Signatures[0].X5TS256)
+
+	b, err := env.Marshal()
+	assert.NoError(s.T(), err)
+	ref, err := UnmarshalEnvelopeT(b)
+	assert.NoError(s.T(), err)
+	results, err := s.verifier(s.roots).Verify(ref)
+	assert.NoError(s.T(), err)
+	assert.Len(s.T(), results[0].Chain, 3)
+	assert.Equal(s.T(), CertFingerprint(s.leaf), results[0].Key.ID)
+}
+
+func (s *X509Suite) TestFingerprintOnly() {
+	env, err := s.signer(false).SignSimpleEnvelope(s.envelope("spiffe://acme/orders"))
+	assert.NoError(s.T(), err)
+	assert.Empty(s.T(), env.Signatures[0].X5C)
+
+	cv := s.verifier(s.roots)
+	_, err = cv.Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrPolicyNotSatisfied))
+
+	cv.AddCertificates(s.leaf, s.intermediate)
+	_, err = cv.Verify(env)
+	assert.NoError(s.T(), err)
+}
+
+func (s *X509Suite) TestSrcMustMatchSAN() {
+	env, err := s.signer(true).SignSimpleEnvelope(s.envelope("spiffe://acme/billing"))
+	assert.NoError(s.T(), err)
+	results, err := s.verifier(s.roots).Verify(env)
+	assert.Error(s.T(), err)
+	assert.True(s.T(), errors.Is(results[0].Err, ErrCertSrcMismatch))
+}
+
+func (s *X509Suite) TestUnknownRoot() {
+	env, err := s.signer(true).SignSimpleEnvelope(s.envelope("spiffe://acme/orders"))
+	assert.NoError(s.T(), err)
+	other, _ := s.certificate("other", "", true, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(other)
+	results, err := s.verifier(roots).Verify(env)
+	assert.Error(s.T(), err)
+	assert.True(s.T(), errors.Is(results[0].Err, ErrCertificateChain))
+}
+
+func (s *X509Suite) TestTamperedEnvelope() {
+	env, err := s.signer(true).SignSimpleEnvelope(s.envelope("spiffe://acme/orders"))
+	assert.NoError(s.T(), err)
+	env.TTL = 3
+	_, err = s.verifier(s.roots).Verify(env)
+	assert.True(s.T(), errors.Is(err, ErrSignatureInvalid))
+}
+
+func (s *X509Suite) TestKeyMustMatchCertificate() {
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, err := NewCertSigner(other, []*x509.Certificate{s.leaf}, true)
+	assert.True(s.T(), errors.Is(err, ErrCertKeyMismatch))
+}
+
+func (s *X509Suite) TestBackdatedSignature() {
+	env, err := s.signer(true).SignSimpleEnvelope(s.envelope("spiffe://acme/orders"))
+	assert.NoError(s.T(), err)
+	// the certificates expired before the verification
+	cv := s.verifier(s.roots)
+	cv.TimeGenerator = offsetTimer(2 * time.Hour)
+	results, err := cv.Verify(env)
+	assert.Error(s.T(), err)
+	assert.True(s.T(), errors.Is(results[0].Err, ErrCertificateChain))
+
+	// claims to be signed before the leaf was valid
+	env.Signatures[0].T = float64(s.leaf.NotBefore.Add(-time.Minute).UnixMilli())
+	results, err = s.verifier(s.roots).Verify(env)
+	assert.Error(s.T(), err)
+	assert.True(s.T(), errors.Is(results[0].Err, ErrCertificateChain))
+}
+
+func (s *X509Suite) TestKeyUsages() {
+	env, err := s.signer(true).SignSimpleEnvelope(s.envelope("spiffe://acme/orders"))
+	assert.NoError(s.T(), err)
+	cv := s.verifier(s.roots)
+	cv.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	_, err = cv.Verify(env)
+	assert.NoError(s.T(), err)
+	cv.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	results, err := cv.Verify(env)
+	assert.Error(s.T(), err)
+	assert.True(s.T(), errors.Is(results[0].Err, ErrCertificateChain))
+}
+
+func TestX509Suite(t *testing.T) {
+	suite.Run(t, new(X509Suite))
+}
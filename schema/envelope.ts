@@ -1,11 +1,23 @@
 import { Payload } from './payload';
 
+export interface Signature {
+  readonly alg: string;
+  readonly kid: string;
+  readonly src: string; // identity of the signer, looked up in the keyring
+  readonly t: number; // signing time in milliseconds since 1970
+  readonly counter?: boolean; // signs the previous signatures as well
+  readonly sig: string; // base64url
+  readonly x5c?: string[]; // base64 DER certificate chain, leaf first
+  readonly 'x5t#S256'?: string; // base64url SHA-256 fingerprint of the leaf
+}
+
 export interface Envelope<T = unknown> {
-  readonly v: 'A'; // version never ever change, chuck norris rules this
+  readonly v: 'A'; // is the version, never ever change, chuck norris rules this
   readonly id: string;
   readonly src: string;
   readonly dst: string[];
-  readonly t: number; //UTC Nanoseconds since 1970
-  readonly ttl: number; //Limit the hop count
+  readonly t: number; // UTC milliseconds since 1970
+  readonly ttl: number; // limits the hop count
   readonly data: Payload<T>;
+  readonly signatures?: Signature[];
 }